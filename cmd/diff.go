@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flowmitry/syncai/internal/util"
+	"github.com/flowmitry/syncai/pkg/syncai"
+)
+
+func diffCommand() *command {
+	c := newCommand("diff", "<item>", "Show what a sync would change for an item, e.g. `go` or `rules:go` for a rule, `context` or `ignore`.")
+	cf := addConfigFlags(c.flags)
+	c.run = func(args []string) error {
+		if len(args) != 1 {
+			c.usage()
+			return fmt.Errorf("diff expects exactly one item")
+		}
+		sync, err := cf.open()
+		if err != nil {
+			return err
+		}
+		item, ok := findItem(sync.Items(), args[0])
+		if !ok {
+			return fmt.Errorf("item %q not found", args[0])
+		}
+		newest, _ := item.Newest()
+		previews, err := sync.Preview(newest.Path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: latest copy is %s\n", item.Properties, newest.Path)
+		changed := false
+		for _, p := range previews {
+			if !p.Changed() {
+				continue
+			}
+			changed = true
+			current := p.Path
+			if !p.Exists {
+				current = "/dev/null"
+			}
+			fmt.Print(util.Diff(current, p.Path+" (synced)", p.Current, p.Proposed))
+		}
+		if !changed {
+			fmt.Println("All agents are in sync")
+		}
+		return nil
+	}
+	return c
+}
+
+func checkCommand() *command {
+	c := newCommand("check", "", "Exit with an error if any agent is out of sync (useful for CI).")
+	cf := addConfigFlags(c.flags)
+	c.run = func(args []string) error {
+		sync, err := cf.open()
+		if err != nil {
+			return err
+		}
+		report := sync.Status()
+		outOfSync := 0
+		for _, item := range report.Items {
+			if item.Error != "" {
+				outOfSync++
+				fmt.Printf("%s: %s\n", item.Item, item.Error)
+			}
+			for _, a := range item.Agents {
+				if a.InSync {
+					continue
+				}
+				outOfSync++
+				if a.Path == "" {
+					// The agent's rules patterns cannot hold the nested rule
+					fmt.Printf("%s: agent %s is %s, add `**` or \"flatten\" to its rules\n", item.Item, a.Agent, a.State)
+					continue
+				}
+				fmt.Printf("%s: %s is %s, latest is %s\n", item.Item, a.Path, a.State, item.Latest)
+			}
+		}
+		if outOfSync > 0 {
+			return fmt.Errorf("%d file(s) out of sync, run `syncai sync` to fix", outOfSync)
+		}
+		fmt.Println("All agents are in sync")
+		return nil
+	}
+	return c
+}
+
+// findItem looks an item up by its name; a bare name refers to a rule stem.
+func findItem(items []syncai.Item, name string) (syncai.Item, bool) {
+	for _, item := range items {
+		if item.Properties.String() == name {
+			return item, true
+		}
+	}
+	if !strings.Contains(name, ":") {
+		for _, item := range items {
+			if item.Properties.String() == "rules:"+name {
+				return item, true
+			}
+		}
+	}
+	return syncai.Item{}, false
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/flowmitry/syncai/internal/util"
+	"github.com/flowmitry/syncai/pkg/syncai"
+)
+
+func initCommand() *command {
+	c := newCommand("init", "", "Create a default configuration file.")
+	var path string
+	var force bool
+	var global bool
+	c.flags.StringVar(&path, "config", "syncai.json", "path of the configuration file to create")
+	c.flags.BoolVar(&force, "force", false, "overwrite an existing configuration file")
+	c.flags.BoolVar(&global, "global", false, "create the user-level configuration in ~/.config/syncai for files in the home directory")
+	c.run = func(args []string) error {
+		template := syncai.ConfigTemplate()
+		if global {
+			var err error
+			if path, err = syncai.GlobalConfigPath(); err != nil {
+				return err
+			}
+			template = syncai.GlobalConfigTemplate()
+		}
+		if util.IsFileExists(path) && !force {
+			return fmt.Errorf("%s already exists; use -force to overwrite", path)
+		}
+		if err := util.WriteFile(path, template); err != nil {
+			return err
+		}
+		fmt.Printf("Created %s, adjust the agents and run `syncai`\n", path)
+		return nil
+	}
+	return c
+}
+
+func validateCommand() *command {
+	c := newCommand("validate", "", "Check the configuration file for errors.")
+	cf := addConfigFlags(c.flags)
+	c.run = func(args []string) error {
+		if err := cf.resolve(); err != nil {
+			return err
+		}
+		cfg, err := syncai.LoadConfig(cf.path, cf.workDir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Configuration %s is valid: %d agent(s), base path %s\n", cf.path, len(cfg.Agents), cfg.WorkingDir())
+		return nil
+	}
+	return c
+}
@@ -3,204 +3,249 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syncai/internal/model"
-	"syncai/internal/version"
-	"syscall"
-	"time"
-
-	"syncai/internal/config"
-	"syncai/internal/selfupdate"
-	"syncai/internal/syncai"
-	"syncai/internal/util"
+	"strings"
+
+	"github.com/flowmitry/syncai/internal/version"
+	"github.com/flowmitry/syncai/pkg/syncai"
 )
 
-func main() {
-	var cfgPath string
-	var doSelfUpdate bool
-	var showVersion bool
-	var noWatch bool
-	var workingDir string
-	var help bool
-	flag.StringVar(&cfgPath, "config", "syncai.json", "path to configuration file")
-	flag.StringVar(&workingDir, "workdir", "", "base working directory for relative paths (overrides config)")
-	flag.BoolVar(&doSelfUpdate, "self-update", false, "update SyncAI to the latest released version")
-	flag.BoolVar(&noWatch, "no-watch", false, "run only the initial sync")
-	flag.BoolVar(&showVersion, "version", false, "print version and exit")
-	flag.BoolVar(&help, "help", false, "show available commands and their descriptions")
-	flag.Parse()
-
-	if help {
-		fmt.Println("SyncAI - a lightweight utility that keeps AI-assistant guidelines, rules and ignored files in sync across multiple agents:\n")
-		fmt.Println("GitHub: https://github.com/flowmitry/syncai/")
-		fmt.Println("Version: ", version.Version(), "\n")
-		fmt.Println("Available commands:")
-		fmt.Println("  -config string")
-		fmt.Println("        path to configuration file (default \"syncai.json\")")
-		fmt.Println("  -workdir string")
-		fmt.Println("        base working directory for relative paths (overrides config)")
-		fmt.Println("  -self-update")
-		fmt.Println("        update SyncAI to the latest released version")
-		fmt.Println("  -no-watch")
-		fmt.Println("        run only the initial sync")
-		fmt.Println("  -version")
-		fmt.Println("        print version and exit")
-		fmt.Println("  -help")
-		fmt.Println("        show available commands and their descriptions")
-		return
+// command is a single `syncai <name>` subcommand with its own flag set.
+type command struct {
+	name    string
+	args    string
+	summary string
+	flags   *flag.FlagSet
+	run     func(args []string) error
+}
+
+func newCommand(name, args, summary string) *command {
+	c := &command{name: name, args: args, summary: summary}
+	c.flags = flag.NewFlagSet(name, flag.ExitOnError)
+	c.flags.Usage = c.usage
+	return c
+}
+
+func (c *command) usage() {
+	out := c.flags.Output()
+	synopsis := "syncai " + c.name
+	hasFlags := false
+	c.flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		synopsis += " [flags]"
+	}
+	if c.args != "" {
+		synopsis += " " + c.args
+	}
+	fmt.Fprintf(out, "Usage: %s\n\n%s\n", synopsis, c.summary)
+	if hasFlags {
+		fmt.Fprintln(out, "\nFlags:")
+		c.flags.PrintDefaults()
+	}
+}
+
+func commands() []*command {
+	return []*command{
+		watchCommand(),
+		syncCommand(),
+		statusCommand(),
+		diffCommand(),
+		checkCommand(),
+		initCommand(),
+		validateCommand(),
+		trashCommand(),
+		undoCommand(),
+		selfUpdateCommand(),
+		versionCommand(),
 	}
+}
 
-	if showVersion {
-		fmt.Println(version.Version())
+func main() {
+	cmds := commands()
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		runLegacy(cmds, args)
 		return
 	}
 
-	if doSelfUpdate {
-		if err := selfupdate.Run(); err != nil {
-			log.Fatalf("self-update failed: %v", err)
+	name := args[0]
+	if name == "help" {
+		if len(args) > 1 {
+			if c := findCommand(cmds, args[1]); c != nil {
+				c.flags.SetOutput(os.Stdout)
+				c.usage()
+				return
+			}
 		}
-		fmt.Println("SyncAI updated successfully. Restart if it was running.")
+		printHelp(os.Stdout, cmds, nil)
 		return
 	}
-
-	cfg, err := config.Load(cfgPath, workingDir)
-	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+	c := findCommand(cmds, name)
+	if c == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printHelp(os.Stderr, cmds, nil)
+		os.Exit(2)
 	}
-
-	fmt.Printf("SyncAI %s\nGitHub: https://github.com/flowmitry/syncai/\n\n", version.Version())
-	fmt.Println("Config path: <", cfgPath, ">")
-	err = os.Chdir(cfg.WorkingDir())
-	if err != nil {
-		log.Fatalf("failed to chdir to %q: %v", cfg.WorkingDir(), err)
+	_ = c.flags.Parse(args[1:])
+	if err := c.run(c.flags.Args()); err != nil {
+		log.Fatalf("%s failed: %v", c.name, err)
 	}
-	fmt.Println("Base path: <", cfg.WorkingDir(), ">")
-
-	sync := syncai.New(cfg)
-	initialSync(cfg, sync)
+}
 
-	if noWatch {
-		fmt.Println("SyncAI completed the initial sync")
+// runLegacy keeps the flag-only invocation working: `syncai [-config f] [-workdir d] [-no-watch]`
+// runs watch (or sync with -no-watch), and -version, -self-update and -help map to their commands.
+func runLegacy(cmds []*command, args []string) {
+	fs := flag.NewFlagSet("syncai", flag.ExitOnError)
+	cf := addConfigFlags(fs)
+	lf := addLogFlags(fs)
+	var doSelfUpdate bool
+	var showVersion bool
+	var noWatch bool
+	var help bool
+	fs.BoolVar(&doSelfUpdate, "self-update", false, "update SyncAI to the latest released version")
+	fs.BoolVar(&noWatch, "no-watch", false, "run only the initial sync")
+	fs.BoolVar(&showVersion, "version", false, "print version and exit")
+	fs.BoolVar(&help, "help", false, "show available commands and their descriptions")
+	fs.Usage = func() { printHelp(fs.Output(), cmds, fs) }
+	_ = fs.Parse(args)
+
+	var err error
+	switch {
+	case help:
+		printHelp(os.Stdout, cmds, fs)
 		return
+	case showVersion:
+		err = findCommand(cmds, "version").run(nil)
+	case doSelfUpdate:
+		err = findCommand(cmds, "self-update").run(nil)
+	default:
+		err = runSync(cf, lf, !noWatch)
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
+}
 
-	fmt.Println("Start watching for file changes...")
-	filesState := buildFilesState(cfg)
-	scan := func() {
-		newState := make(map[string]string)
-		for _, agent := range cfg.Agents {
-			for _, path := range agent.Files() {
-				_, err := os.Stat(path)
-				if err != nil {
-					if os.IsNotExist(err) {
-						// File may not exist yet; silently ignore
-						continue
-					}
-					log.Printf("file error %s: %v", path, err)
-					continue
-				}
-				hash, _ := util.FileHash(path)
-				newState[path] = hash
-				prev, ok := filesState[path]
-				filesState[path] = hash
-				if !ok || hash != prev {
-					if ok {
-						log.Printf("Detected change in %s (%s), syncing...", path, agent.Name)
-					} else {
-						log.Printf("Detected new file %s (%s), syncing...", path, agent.Name)
-					}
-					updatedFiles, err := sync.Sync(path)
-					if err != nil {
-						log.Printf("sync error: %v", err)
-					}
-					for _, newPath := range updatedFiles {
-						filesState[newPath], _ = util.FileHash(newPath)
-					}
-				}
-			}
-		}
-		for path := range filesState {
-			if _, ok := newState[path]; !ok {
-				deletedPaths, err := sync.Delete(path)
-				for _, deletedPath := range deletedPaths {
-					log.Printf("Deleted file across agents: %s", deletedPath)
-					delete(filesState, deletedPath)
-				}
-				if err != nil {
-					log.Printf("delete error: %v", err)
-				}
-			}
+func findCommand(cmds []*command, name string) *command {
+	for _, c := range cmds {
+		if c.name == name {
+			return c
 		}
 	}
+	return nil
+}
+
+func printHelp(out io.Writer, cmds []*command, legacy *flag.FlagSet) {
+	fmt.Fprint(out, "SyncAI - a lightweight utility that keeps AI-assistant guidelines, rules and ignored files in sync across multiple agents\n\n")
+	fmt.Fprintln(out, "GitHub: https://github.com/flowmitry/syncai/")
+	fmt.Fprint(out, "Version: ", version.Version(), "\n\n")
+	fmt.Fprint(out, "Usage: syncai <command> [flags] [arguments]\n\n")
+	fmt.Fprintln(out, "Commands:")
+	for _, c := range cmds {
+		fmt.Fprintf(out, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprint(out, "\nRun 'syncai help <command>' for the flags of a command.\n")
+	if legacy != nil {
+		fmt.Fprint(out, "\nWithout a command, SyncAI watches for changes and accepts these flags:\n")
+		legacy.SetOutput(out)
+		legacy.PrintDefaults()
+	}
+}
 
-	ticker := time.NewTicker(cfg.Interval())
-	defer ticker.Stop()
+// configFlags holds the -config, -workdir and -global flags shared by commands that need a configuration.
+type configFlags struct {
+	path    string
+	workDir string
+	global  bool
+}
 
-	// Handle OS signals to terminate gracefully
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	cf := &configFlags{}
+	fs.StringVar(&cf.path, "config", "syncai.json", "path to configuration file")
+	fs.StringVar(&cf.workDir, "workdir", "", "base working directory for relative paths (overrides config)")
+	fs.BoolVar(&cf.global, "global", false, "use the user-level configuration in ~/.config/syncai instead of -config")
+	return cf
+}
 
-	for {
-		select {
-		case <-ticker.C:
-			scan()
-		case <-sigCh:
-			log.Println("Exiting SyncAI...")
-			return
-		}
+// resolve points the configuration path at the user-level configuration when -global is set.
+func (cf *configFlags) resolve() error {
+	if !cf.global {
+		return nil
+	}
+	path, err := syncai.GlobalConfigPath()
+	if err != nil {
+		return err
 	}
+	cf.path = path
+	return nil
 }
 
-// Initial sync: pick the newest version among agents for each logical file (by kind+stem) and propagate it
-func initialSync(cfg config.Config, sync *syncai.SyncAI) {
-	log.Println("Initial sync started...")
-	type newest struct {
-		path string
-		mod  time.Time
+// load loads the configuration. Its paths are resolved against the working directory of
+// the configuration, so the process itself never changes directory.
+func (cf *configFlags) load() (syncai.Config, error) {
+	if err := cf.resolve(); err != nil {
+		return syncai.Config{}, err
 	}
-	latest := make(map[string]newest)
-
-	for _, agent := range cfg.Agents {
-		for _, path := range agent.Files() {
-			fi, err := os.Stat(path)
-			if err != nil {
-				// File might not exist yet; skip
-				continue
-			}
-			modT := fi.ModTime()
+	cfg, err := syncai.LoadConfig(cf.path, cf.workDir)
+	if err != nil {
+		return syncai.Config{}, fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg, nil
+}
 
-			_, kind, stem := sync.Identify(path)
-			if kind == model.KindUnknown {
-				continue
-			}
+// reload loads the configuration file again after load, keeping the working directory of
+// the running configuration; changing it requires a restart.
+func (cf *configFlags) reload(workDir string) (syncai.Config, error) {
+	return syncai.LoadConfig(cf.path, workDir)
+}
 
-			key := string(kind) + "|" + stem
-			if cur, ok := latest[key]; !ok || modT.After(cur.mod) {
-				latest[key] = newest{path: path, mod: modT}
-			}
-		}
+// open loads the configuration and creates a SyncAI instance for it.
+func (cf *configFlags) open(opts ...syncai.Option) (*syncai.SyncAI, error) {
+	cfg, err := cf.load()
+	if err != nil {
+		return nil, err
 	}
+	return syncai.New(cfg, opts...)
+}
 
-	for _, v := range latest {
-		if _, err := sync.Sync(v.path); err != nil {
-			log.Printf("initial sync error for %s: %v", v.path, err)
-		}
-	}
-	log.Println("Initial sync completed.")
+// logFlags holds the logging flags of commands that run syncs.
+type logFlags struct {
+	level  string
+	format string
+	quiet  bool
 }
 
-func buildFilesState(cfg config.Config) map[string]string {
-	hashes := make(map[string]string)
-	for _, agent := range cfg.Agents {
-		for _, path := range agent.Files() {
-			if h, err := util.FileHash(path); err != nil {
-				log.Printf("hash error %s: %v", path, err)
-			} else {
-				hashes[path] = h
-			}
-		}
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	lf := &logFlags{}
+	fs.StringVar(&lf.level, "log-level", "info", "minimum log level: debug, info, warn or error")
+	fs.StringVar(&lf.format, "log-format", "text", "log format: text or json")
+	fs.BoolVar(&lf.quiet, "quiet", false, "only log warnings and errors")
+	return lf
+}
+
+// setup builds the logger described by the flags and installs it as the default logger.
+func (lf *logFlags) setup() (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(lf.level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", lf.level)
+	}
+	if lf.quiet {
+		level = max(level, slog.LevelWarn)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch lf.format {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", lf.format)
 	}
-	return hashes
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	return logger, nil
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+func statusCommand() *command {
+	c := newCommand("status", "", "Show every synced item and which agents are in or out of sync.")
+	cf := addConfigFlags(c.flags)
+	var format string
+	c.flags.StringVar(&format, "format", "text", "output format: text or json")
+	c.run = func(args []string) error {
+		if format != "text" && format != "json" {
+			return fmt.Errorf("unknown format %q", format)
+		}
+		sync, err := cf.open()
+		if err != nil {
+			return err
+		}
+		report := sync.Status()
+
+		if format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(report)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ITEM\tAGENT\tPATH\tSTATE\tBODY\tMETADATA")
+		for _, item := range report.Items {
+			if item.Error != "" {
+				fmt.Fprintf(w, "%s\t\t\terror: %s\t\t\n", item.Item, item.Error)
+			}
+			for _, a := range item.Agents {
+				body := ""
+				if a.BodyHash != "" {
+					body = a.BodyHash[:12]
+				}
+				keys := make([]string, 0, len(a.MetadataDiff))
+				for _, d := range a.MetadataDiff {
+					keys = append(keys, d.Key)
+				}
+				state := a.State
+				if a.HandEdited {
+					state += ", edited by hand"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", item.Item, a.Agent, a.Path, state, body, strings.Join(keys, ","))
+			}
+		}
+		return w.Flush()
+	}
+	return c
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/selfupdate"
+	"github.com/flowmitry/syncai/internal/util"
+	"github.com/flowmitry/syncai/internal/version"
+	"github.com/flowmitry/syncai/pkg/syncai"
+)
+
+func watchCommand() *command {
+	c := newCommand("watch", "", "Sync all agents once, then keep watching for changes (default).")
+	cf := addConfigFlags(c.flags)
+	lf := addLogFlags(c.flags)
+	var workspace string
+	c.flags.StringVar(&workspace, "workspace", "", "path to a workspace file listing several project configurations to watch")
+	c.run = func(args []string) error {
+		if workspace != "" {
+			return runWorkspace(workspace, lf, true)
+		}
+		return runSync(cf, lf, true)
+	}
+	return c
+}
+
+func syncCommand() *command {
+	c := newCommand("sync", "", "Sync all agents once and exit.")
+	cf := addConfigFlags(c.flags)
+	lf := addLogFlags(c.flags)
+	var dryRun bool
+	var workspace string
+	c.flags.BoolVar(&dryRun, "dry-run", false, "only log the files that would be written")
+	c.flags.StringVar(&workspace, "workspace", "", "path to a workspace file listing several project configurations to sync")
+	c.run = func(args []string) error {
+		switch {
+		case dryRun && workspace != "":
+			return fmt.Errorf("-dry-run cannot be combined with -workspace")
+		case dryRun:
+			return runDryRun(cf, lf)
+		case workspace != "":
+			return runWorkspace(workspace, lf, false)
+		}
+		return runSync(cf, lf, false)
+	}
+	return c
+}
+
+func runSync(cf *configFlags, lf *logFlags, watch bool) error {
+	logger, err := lf.setup()
+	if err != nil {
+		return err
+	}
+	printBanner(lf)
+	ctx, stop := signalContext()
+	defer stop()
+	return runProject(ctx, cf, logger, watch)
+}
+
+func printBanner(lf *logFlags) {
+	if !lf.quiet && lf.format == "text" {
+		fmt.Printf("SyncAI %s\nGitHub: https://github.com/flowmitry/syncai/\n\n", version.Version())
+	}
+}
+
+// signalContext returns a context that is cancelled on SIGINT or SIGTERM, so syncing stops
+// gracefully; a second signal terminates immediately.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	return ctx, stop
+}
+
+// runProject syncs the agents of one configuration and, with watch, keeps watching them
+// until ctx is cancelled.
+func runProject(ctx context.Context, cf *configFlags, logger *slog.Logger, watch bool) error {
+	cfg, err := cf.load()
+	if err != nil {
+		return err
+	}
+	logger.Info("Configuration loaded", "event", "config_loaded", "config", cf.path, "workdir", cfg.WorkingDir())
+
+	sync, err := syncai.New(cfg, syncai.WithLogger(logger))
+	if err != nil {
+		return err
+	}
+	if err := sync.SyncAll(ctx); err != nil {
+		logger.Info("Exiting SyncAI", "event", "exit")
+		return nil
+	}
+
+	if !watch {
+		logger.Info("SyncAI completed the initial sync", "event", "done")
+		return nil
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go watchConfig(ctx, hup, cf, sync, logger)
+
+	logger.Info("Start watching for file changes", "event", "watch_start", "interval", cfg.Interval())
+	sync.Watch(ctx)
+	logger.Info("Exiting SyncAI", "event", "exit")
+	return nil
+}
+
+// watchConfig reloads the configuration file whenever its content changes or SIGHUP is received.
+func watchConfig(ctx context.Context, hup <-chan os.Signal, cf *configFlags, sync *syncai.SyncAI, logger *slog.Logger) {
+	lastHash, _ := util.FileHash(cf.path)
+	ticker := time.NewTicker(sync.Config().Interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			hash, err := util.FileHash(cf.path)
+			if err != nil || hash == lastHash {
+				// A missing file is most likely being replaced; wait for the new one
+				continue
+			}
+			lastHash = hash
+			logger.Info("Configuration file changed, reloading", "event", "config_reload", "config", cf.path)
+		case <-hup:
+			lastHash, _ = util.FileHash(cf.path)
+			logger.Info("Reloading configuration", "event", "config_reload", "config", cf.path)
+		}
+		cfg, err := cf.reload(sync.Root())
+		if err == nil {
+			err = sync.Reload(ctx, cfg)
+		}
+		if err != nil {
+			logger.Error("Configuration reload failed, keeping the current configuration", "event", "config_error", "config", cf.path, "error", err)
+			continue
+		}
+		ticker.Reset(cfg.Interval())
+	}
+}
+
+func runDryRun(cf *configFlags, lf *logFlags) error {
+	logger, err := lf.setup()
+	if err != nil {
+		return err
+	}
+	sync, err := cf.open(syncai.WithLogger(logger))
+	if err != nil {
+		return err
+	}
+	changes := 0
+	unsubscribe := sync.Subscribe(func(e syncai.Event) {
+		if e.Type == "would_sync" {
+			changes++
+		}
+	})
+	defer unsubscribe()
+	if err := sync.SyncAll(context.Background(), syncai.DryRun()); err != nil {
+		return err
+	}
+	fmt.Printf("%d file(s) would be written\n", changes)
+	return nil
+}
+
+func selfUpdateCommand() *command {
+	c := newCommand("self-update", "", "Update SyncAI to the latest released version.")
+	c.run = func(args []string) error {
+		if err := selfupdate.Run(); err != nil {
+			return fmt.Errorf("self-update failed: %w", err)
+		}
+		fmt.Println("SyncAI updated successfully. Restart if it was running.")
+		return nil
+	}
+	return c
+}
+
+func versionCommand() *command {
+	c := newCommand("version", "", "Print the version and exit.")
+	c.run = func(args []string) error {
+		fmt.Println(version.Version())
+		return nil
+	}
+	return c
+}
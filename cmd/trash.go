@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/flowmitry/syncai/pkg/syncai"
+)
+
+func trashCommand() *command {
+	c := newCommand("trash", "list | restore <id>", "List files deleted by SyncAI or restore a trash entry to the original paths.")
+	cf := addConfigFlags(c.flags)
+	var force bool
+	c.flags.BoolVar(&force, "force", false, "overwrite existing files when restoring")
+	c.run = func(args []string) error {
+		if len(args) == 0 {
+			c.usage()
+			return fmt.Errorf("missing action")
+		}
+		// Allow flags after the action, e.g. `syncai trash restore -force <id>`.
+		action := args[0]
+		if err := c.flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		args = c.flags.Args()
+
+		cfg, err := cf.load()
+		if err != nil {
+			return err
+		}
+		t := syncai.OpenTrash(cfg)
+
+		switch action {
+		case "list":
+			entries, err := t.List()
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				fmt.Println("Trash is empty")
+				return nil
+			}
+			for _, e := range entries {
+				fmt.Printf("%s  %s\n", e.ID, e.Time.Local().Format(time.DateTime))
+				for _, f := range e.Files {
+					fmt.Printf("    %s\n", f)
+				}
+			}
+			return nil
+		case "restore":
+			if len(args) != 1 {
+				c.usage()
+				return fmt.Errorf("restore expects exactly one trash id")
+			}
+			restored, err := t.Restore(args[0], force)
+			for _, path := range restored {
+				fmt.Printf("Restored %s\n", path)
+			}
+			return err
+		default:
+			c.usage()
+			return fmt.Errorf("unknown action %q", action)
+		}
+	}
+	return c
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/flowmitry/syncai/pkg/syncai"
+)
+
+func undoCommand() *command {
+	c := newCommand("undo", "[id]", "Roll back the last sync, or the sync with the given id, across all agents.")
+	cf := addConfigFlags(c.flags)
+	lf := addLogFlags(c.flags)
+	var force bool
+	var list bool
+	c.flags.BoolVar(&force, "force", false, "undo even if files changed after the sync")
+	c.flags.BoolVar(&list, "list", false, "list recorded syncs instead of undoing")
+	c.run = func(args []string) error {
+		if len(args) > 1 {
+			c.usage()
+			return fmt.Errorf("too many arguments")
+		}
+		logger, err := lf.setup()
+		if err != nil {
+			return err
+		}
+		sync, err := cf.open(syncai.WithLogger(logger))
+		if err != nil {
+			return err
+		}
+		if sync.Journal() == nil {
+			return fmt.Errorf("journal is disabled in the configuration")
+		}
+
+		if list {
+			records, err := sync.Journal().Records()
+			if err != nil {
+				return err
+			}
+			for i := len(records) - 1; i >= 0; i-- {
+				r := records[i]
+				if r.Type == syncai.RecordUndo {
+					fmt.Printf("%s  %s  undo of %s\n", r.ID, r.Time.Local().Format(time.DateTime), r.Undoes)
+					continue
+				}
+				fmt.Printf("%s  %s  sync from %s\n", r.ID, r.Time.Local().Format(time.DateTime), r.Source)
+				for _, ch := range r.Changes {
+					fmt.Printf("    %s\n", ch.Path)
+				}
+			}
+			return nil
+		}
+
+		id := ""
+		if len(args) == 1 {
+			id = args[0]
+		}
+		rec, err := sync.Undo(id, force)
+		if err != nil {
+			return err
+		}
+		for _, ch := range rec.Changes {
+			fmt.Printf("Reverted %s\n", ch.Path)
+		}
+		fmt.Printf("Sync %s from %s has been undone\n", rec.ID, rec.Source)
+		return nil
+	}
+	return c
+}
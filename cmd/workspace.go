@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/flowmitry/syncai/pkg/syncai"
+)
+
+// runWorkspace runs every project of a workspace file in this process, each with its own
+// SyncAI instance and a logger that tags its events with the project name. A project that
+// fails to load is logged and skipped; the others keep running.
+func runWorkspace(path string, lf *logFlags, watch bool) error {
+	logger, err := lf.setup()
+	if err != nil {
+		return err
+	}
+	ws, err := syncai.LoadWorkspace(path)
+	if err != nil {
+		return err
+	}
+	printBanner(lf)
+	ctx, stop := signalContext()
+	defer stop()
+	logger.Info("Workspace loaded", "event", "workspace_loaded", "workspace", path, "projects", len(ws.Projects))
+
+	errs := make([]error, len(ws.Projects))
+	var wg sync.WaitGroup
+	for i, p := range ws.Projects {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			projectLog := logger.With("project", p.Name)
+			cf := &configFlags{path: p.Config, workDir: p.WorkDir}
+			if err := runProject(ctx, cf, projectLog, watch); err != nil {
+				projectLog.Error("Project could not be synced", "event", "project_error", "config", p.Config, "error", err)
+				errs[i] = fmt.Errorf("project %s: %w", p.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
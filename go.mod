@@ -1,4 +1,4 @@
-module syncai
+module github.com/flowmitry/syncai
 
 go 1.22
 
@@ -1,24 +1,97 @@
 package config
 
 import (
+	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/flowmitry/syncai/internal/model"
+	"github.com/flowmitry/syncai/internal/util"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
+//go:embed syncai.default.json
+var defaultConfig []byte
+
+//go:embed syncai.global.json
+var globalConfig []byte
+
+// Template returns the default configuration written by `syncai init`.
+func Template() []byte {
+	return defaultConfig
+}
+
+// GlobalTemplate returns the default user-level configuration written by `syncai init -global`.
+func GlobalTemplate() []byte {
+	return globalConfig
+}
+
+// GlobalPath returns the location of the user-level configuration that keeps the agents'
+// home directory files in sync: $XDG_CONFIG_HOME/syncai/syncai.json, or
+// ~/.config/syncai/syncai.json when XDG_CONFIG_HOME is not set.
+func GlobalPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "syncai", "syncai.json"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("locate home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "syncai", "syncai.json"), nil
+}
+
+// Rules lists where an agent keeps its rule files. Pattern and Patterns may be combined;
+// new files are created with the first pattern that fits. Flatten lets patterns without
+// `**` hold nested rules, see RulesPattern.
 type Rules struct {
-	Pattern string `json:"pattern"`
+	Pattern  string   `json:"pattern"`
+	Patterns []string `json:"patterns"`
+	Flatten  bool     `json:"flatten,omitempty"`
 }
 
+// All returns every configured pattern, Pattern first.
+func (r Rules) All() []string {
+	all := make([]string, 0, len(r.Patterns)+1)
+	for _, p := range append([]string{r.Pattern}, r.Patterns...) {
+		if p = strings.TrimSpace(p); p != "" {
+			all = append(all, p)
+		}
+	}
+	return all
+}
+
+// Parsed returns the valid patterns of All, parsed.
+func (r Rules) Parsed() []RulesPattern {
+	parsed := make([]RulesPattern, 0, len(r.Patterns)+1)
+	for _, raw := range r.All() {
+		if p, err := ParseRulesPattern(raw); err == nil {
+			p.Flatten = r.Flatten
+			parsed = append(parsed, p)
+		}
+	}
+	return parsed
+}
+
+// Context is the agent's main instruction file. A path such as `**/AGENTS.md` also
+// covers the file in every subdirectory; see ContextPattern.
 type Context struct {
 	Path string `json:"path"`
 }
 
+// Parsed returns the parsed path, or false when none is configured or it is invalid.
+func (c Context) Parsed() (ContextPattern, bool) {
+	if strings.TrimSpace(c.Path) == "" {
+		return ContextPattern{}, false
+	}
+	p, err := ParseContextPattern(c.Path)
+	return p, err == nil
+}
+
 func (c Context) Index() string {
 	return "Context"
 }
@@ -27,21 +100,86 @@ type Ignore struct {
 	Path string `json:"path"`
 }
 
+// Generator declares an external command that parses and renders the agent's rules
+// instead of a built-in generator. Timeout is in seconds; 0 uses the default of 10.
+// Header adds a comment naming the source file and its hash to every copy written for the
+// agent, whether or not a command is set.
+type Generator struct {
+	Command []string `json:"command"`
+	Timeout int      `json:"timeout"`
+	Header  bool     `json:"header,omitempty"`
+}
+
+func (g Generator) IsExternal() bool {
+	return len(g.Command) > 0
+}
+
+func (g Generator) TimeoutDuration() time.Duration {
+	if g.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(g.Timeout) * time.Second
+}
+
+// Agent is one assistant and the files it reads. A disabled agent, usually one inherited
+// through `extends`, is dropped when the configuration is loaded. Files matched by Exclude
+// (in .gitignore syntax) are neither synced from nor written to the agent.
 type Agent struct {
-	Name    string  `json:"name"`
-	Rules   Rules   `json:"rules"`
-	Context Context `json:"context"`
-	Ignore  Ignore  `json:"ignore"`
+	Name      string    `json:"name"`
+	Disabled  bool      `json:"disabled,omitempty"`
+	Rules     Rules     `json:"rules"`
+	Context   Context   `json:"context"`
+	Ignore    Ignore    `json:"ignore"`
+	Exclude   []string  `json:"exclude,omitempty"`
+	Generator Generator `json:"generator"`
+}
+
+// Trash configures where deleted copies go instead of being removed outright.
+// Retention is the number of days a trash entry is kept; 0 keeps entries forever.
+type Trash struct {
+	Disabled  bool `json:"disabled"`
+	Retention int  `json:"retention"`
+}
+
+// Journal configures the log of sync batches used by `syncai undo`.
+type Journal struct {
+	Disabled bool `json:"disabled"`
+}
+
+// Delete selects which kinds propagate deletions to the other agents.
+// Rules always do; context and ignore files only when enabled here.
+type Delete struct {
+	Context bool `json:"context"`
+	Ignore  bool `json:"ignore"`
 }
 
 type Meta struct {
-	Interval   int    `json:"interval"`
-	WorkingDir string `json:"workdir"`
+	Interval   int      `json:"interval"`
+	WorkingDir string   `json:"workdir"`
+	Metadata   string   `json:"metadata"`
+	Trash      Trash    `json:"trash"`
+	Journal    Journal  `json:"journal"`
+	Delete     Delete   `json:"delete"`
+	Exclude    []string `json:"exclude,omitempty"`
 }
 
+// Config is the content of syncai.json. Extends lists the files and presets it was merged
+// over when it was loaded.
 type Config struct {
-	Meta   Meta    `json:"config"`
-	Agents []Agent `json:"agents"`
+	Extends []string `json:"extends,omitempty"`
+	Meta    Meta     `json:"config"`
+	Agents  []Agent  `json:"agents"`
+}
+
+// dropDisabled removes the disabled agents.
+func (c *Config) dropDisabled() {
+	agents := c.Agents[:0:0]
+	for _, a := range c.Agents {
+		if !a.Disabled {
+			agents = append(agents, a)
+		}
+	}
+	c.Agents = agents
 }
 
 func (c Config) Interval() time.Duration {
@@ -51,10 +189,83 @@ func (c Config) Interval() time.Duration {
 	return time.Duration(c.Meta.Interval) * time.Second
 }
 
+func (c Config) MergePolicy() model.MergePolicy {
+	if c.Meta.Metadata == "" {
+		return model.MergeChanged
+	}
+	return model.MergePolicy(c.Meta.Metadata)
+}
+
 func (c Config) WorkingDir() string {
 	return strings.TrimSuffix(c.Meta.WorkingDir, "/")
 }
 
+// Rooted returns a copy of the configuration for the absolute working directory root, with
+// every agent path in the form SyncAI identifies files by: relative to root when it lies
+// below root, absolute otherwise.
+func (c Config) Rooted(root string) Config {
+	rel := func(p string) string {
+		if p = strings.TrimSpace(p); p == "" {
+			return p
+		}
+		return util.Rel(root, p)
+	}
+	c.Meta.WorkingDir = root
+	agents := make([]Agent, len(c.Agents))
+	for i, a := range c.Agents {
+		a.Context.Path = rel(a.Context.Path)
+		a.Ignore.Path = rel(a.Ignore.Path)
+		a.Rules.Pattern = rel(a.Rules.Pattern)
+		patterns := make([]string, len(a.Rules.Patterns))
+		for j, p := range a.Rules.Patterns {
+			patterns[j] = rel(p)
+		}
+		if a.Rules.Patterns == nil {
+			patterns = nil
+		}
+		a.Rules.Patterns = patterns
+		agents[i] = a
+	}
+	c.Agents = agents
+	return c
+}
+
+// Exclude returns the matcher of the files the agent leaves alone: the global exclude
+// patterns followed by the agent's, so an agent can re-include a file with `!`.
+func (c Config) Exclude(a Agent) (*util.PathMatcher, error) {
+	patterns := append(append([]string(nil), c.Meta.Exclude...), a.Exclude...)
+	return util.NewPathMatcher(patterns)
+}
+
+// StateDir is the directory, relative to the working directory, where SyncAI keeps its own data.
+func (c Config) StateDir() string {
+	return ".syncai"
+}
+
+func (c Config) TrashDir() string {
+	return filepath.Join(c.StateDir(), "trash")
+}
+
+func (c Config) StatePath() string {
+	return filepath.Join(c.StateDir(), "state.json")
+}
+
+func (c Config) JournalPath() string {
+	return filepath.Join(c.StateDir(), "journal.jsonl")
+}
+
+// ObjectsDir holds copies of content overwritten by sync batches, keyed by hash.
+func (c Config) ObjectsDir() string {
+	return filepath.Join(c.StateDir(), "objects")
+}
+
+func (c Config) TrashRetention() time.Duration {
+	if c.Meta.Trash.Retention <= 0 {
+		return 0
+	}
+	return time.Duration(c.Meta.Trash.Retention) * 24 * time.Hour
+}
+
 func Load(configPath, basePath string) (Config, error) {
 	f, err := os.Open(configPath)
 	if err != nil {
@@ -67,12 +278,31 @@ func Load(configPath, basePath string) (Config, error) {
 		return Config{}, fmt.Errorf("read config: %w", err)
 	}
 
+	self, err := filepath.Abs(configPath)
+	if err != nil {
+		self = configPath
+	}
+	doc, extends, err := resolveExtends(data, filepath.Dir(self), []string{self})
+	if err != nil {
+		return Config{}, fmt.Errorf("parse config: %w", err)
+	}
+	if data, err = json.Marshal(doc); err != nil {
+		return Config{}, fmt.Errorf("parse config: %w", err)
+	}
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return Config{}, fmt.Errorf("parse config: %w", err)
 	}
-	if strings.TrimSpace(basePath) != "" {
-		cfg.Meta.WorkingDir = strings.TrimSpace(basePath)
+	cfg.Extends = extends
+	cfg.dropDisabled()
+	if err := cfg.expandPaths(); err != nil {
+		return Config{}, err
+	}
+	if basePath, err = ExpandPath(strings.TrimSpace(basePath)); err != nil {
+		return Config{}, err
+	}
+	if basePath != "" {
+		cfg.Meta.WorkingDir = basePath
 	} else {
 		if strings.TrimSpace(cfg.Meta.WorkingDir) == "" {
 			cfg.Meta.WorkingDir = strings.TrimSpace(filepath.Dir(configPath))
@@ -82,16 +312,84 @@ func Load(configPath, basePath string) (Config, error) {
 	if err := validateWorkingDir(cfg.Meta.WorkingDir); err != nil {
 		return Config{}, fmt.Errorf("working directory error: %w", err)
 	}
+	// Paths in the configuration are resolved against the working directory, not the
+	// directory the process happens to run in.
+	if abs, err := filepath.Abs(cfg.Meta.WorkingDir); err == nil {
+		cfg.Meta.WorkingDir = abs
+	}
 
-	if len(cfg.Agents) == 0 {
-		return Config{}, fmt.Errorf("config has no agents defined")
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
 	}
 
 	return cfg, nil
 }
 
+// Validate checks the agents for mistakes that would make syncing ambiguous,
+// such as duplicate names, shared paths or malformed patterns.
+func (c Config) Validate() error {
+	if len(c.Agents) == 0 {
+		return fmt.Errorf("config has no agents defined")
+	}
+	var errs []error
+	switch c.MergePolicy() {
+	case model.MergeChanged, model.MergeNewestValue:
+	default:
+		errs = append(errs, fmt.Errorf("unknown metadata policy %q, expected %q or %q", c.Meta.Metadata, model.MergeChanged, model.MergeNewestValue))
+	}
+	if _, err := util.NewPathMatcher(c.Meta.Exclude); err != nil {
+		errs = append(errs, fmt.Errorf("exclude: %w", err))
+	}
+	names := make(map[string]bool)
+	paths := make(map[string]string)
+	claim := func(agent, path string) {
+		if path == "" {
+			return
+		}
+		clean := filepath.Clean(path)
+		if other, ok := paths[clean]; ok {
+			errs = append(errs, fmt.Errorf("agent %q: path %s is already used by agent %q; merge the two agents or give each its own path", agent, path, other))
+			return
+		}
+		paths[clean] = agent
+	}
+	for i, a := range c.Agents {
+		name := strings.TrimSpace(a.Name)
+		if name == "" {
+			errs = append(errs, fmt.Errorf("agent #%d has no name", i+1))
+		} else if names[strings.ToLower(name)] {
+			errs = append(errs, fmt.Errorf("agent %q is defined more than once", name))
+		}
+		names[strings.ToLower(name)] = true
+
+		if p := strings.TrimSpace(a.Context.Path); p != "" {
+			if _, err := ParseContextPattern(p); err != nil {
+				errs = append(errs, fmt.Errorf("agent %q: %w", name, err))
+			}
+		}
+		claim(name, strings.TrimSpace(a.Context.Path))
+		claim(name, strings.TrimSpace(a.Ignore.Path))
+		for _, pat := range a.Rules.All() {
+			if _, err := ParseRulesPattern(pat); err != nil {
+				errs = append(errs, fmt.Errorf("agent %q: %w", name, err))
+			}
+			claim(name, pat)
+		}
+		if _, err := util.NewPathMatcher(a.Exclude); err != nil {
+			errs = append(errs, fmt.Errorf("agent %q: exclude: %w", name, err))
+		}
+		if a.Generator.IsExternal() && strings.TrimSpace(a.Generator.Command[0]) == "" {
+			errs = append(errs, fmt.Errorf("agent %q: generator command is empty", name))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
 func validateWorkingDir(basePath string) error {
-	path := strings.TrimSuffix(basePath, "/")
+	path := filepath.Clean(basePath)
 	if info, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("working dir %s does not exist: %w", path, err)
@@ -102,11 +400,20 @@ func validateWorkingDir(basePath string) error {
 	return nil
 }
 
-func (a Agent) Files() []string {
+// Files returns the agent's files that exist below root and are not excluded; a plain
+// context or ignore path is listed even when the file is missing. Paths are relative to
+// root like the configured ones. Patterns and files that cannot be read are logged to log.
+func (a Agent) Files(root string, exclude *util.PathMatcher, log *slog.Logger) []string {
 	files := make([]string, 0, 8)
 
-	// Include context if configured
-	if p := strings.TrimSpace(a.Context.Path); p != "" {
+	// Include context if configured; nested context files only if they exist
+	if p, ok := a.Context.Parsed(); ok && p.Nested {
+		matches, err := p.Glob(root)
+		if err != nil {
+			log.Warn("Context path could not be expanded", "event", "config_error", "agent", a.Name, "pattern", p.Raw, "error", err)
+		}
+		files = append(files, matches...)
+	} else if p := strings.TrimSpace(a.Context.Path); p != "" {
 		files = append(files, p)
 	}
 
@@ -115,22 +422,27 @@ func (a Agent) Files() []string {
 		files = append(files, p)
 	}
 
-	// Include rules only if a non-empty pattern is configured
-	if pat := strings.TrimSpace(a.Rules.Pattern); pat != "" {
-		matches, err := filepath.Glob(pat)
+	// Include rules of every configured pattern; a file matched by several patterns is listed once
+	seen := make(map[string]bool)
+	for _, pat := range a.Rules.Parsed() {
+		matches, err := pat.Glob(root)
 		if err != nil {
-			log.Printf("glob %s: %v", pat, err)
+			log.Warn("Rules pattern could not be expanded", "event", "config_error", "agent", a.Name, "pattern", pat.Raw, "error", err)
 		}
 		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
 			path := strings.TrimSpace(match)
 			if path != "" {
-				_, err := os.Stat(path)
+				_, err := os.Stat(util.Resolve(root, path))
 				if err != nil {
 					if os.IsNotExist(err) {
 						// File may not exist yet; silently ignore
 						continue
 					}
-					log.Printf("file error %s: %v", path, err)
+					log.Warn("File could not be read", "event", "file_error", "agent", a.Name, "src", path, "error", err)
 					continue
 				}
 				files = append(files, path)
@@ -138,5 +450,11 @@ func (a Agent) Files() []string {
 		}
 	}
 
-	return files
+	kept := files[:0]
+	for _, f := range files {
+		if !exclude.Match(f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
 }
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExpandPath expands the placeholders of a configured path: `${VAR}` and `${VAR:-default}`
+// are replaced with environment variables, then a leading `~` with the home directory.
+// The default is used when the variable is unset or empty; an unset variable without a
+// default is an error rather than an empty string, which could turn a path absolute.
+func ExpandPath(path string) (string, error) {
+	expanded, err := expandVars(path)
+	if err != nil {
+		return "", err
+	}
+	return ExpandHome(expanded)
+}
+
+// ExpandHome replaces a leading `~` in path with the user's home directory.
+func ExpandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("expand %s: %w", path, err)
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+func expandVars(path string) (string, error) {
+	var sb strings.Builder
+	rest := path
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			sb.WriteString(rest)
+			return sb.String(), nil
+		}
+		sb.WriteString(rest[:start])
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("expand %s: unterminated ${", path)
+		}
+		expr := rest[start+2 : start+end]
+		rest = rest[start+end+1:]
+
+		name, def, hasDefault := strings.Cut(expr, ":-")
+		if !isVarName(name) {
+			return "", fmt.Errorf("expand %s: invalid variable name %q", path, name)
+		}
+		value, ok := os.LookupEnv(name)
+		switch {
+		case value != "":
+		case hasDefault:
+			value = def
+		case !ok:
+			return "", fmt.Errorf("expand %s: environment variable %s is not set", path, name)
+		}
+		sb.WriteString(value)
+	}
+}
+
+func isVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// expandPaths expands placeholders in the working directory and in the paths and patterns
+// of every agent, so the same file works on machines with different layouts and can reach
+// user-level files such as ~/.claude/CLAUDE.md.
+func (c *Config) expandPaths() error {
+	var errs []error
+	expand := func(p *string) {
+		v, err := ExpandPath(strings.TrimSpace(*p))
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		*p = v
+	}
+	expand(&c.Meta.WorkingDir)
+	for i := range c.Agents {
+		a := &c.Agents[i]
+		expand(&a.Context.Path)
+		expand(&a.Ignore.Path)
+		expand(&a.Rules.Pattern)
+		for j := range a.Rules.Patterns {
+			expand(&a.Rules.Patterns[j])
+		}
+	}
+	return errors.Join(errs...)
+}
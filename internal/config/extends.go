@@ -0,0 +1,182 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// presetPrefix marks an entry of `extends` that names a built-in configuration instead of a file.
+const presetPrefix = "preset:"
+
+// presets are the built-in configurations `extends` can refer to as `preset:<name>`.
+var presets = map[string][]byte{
+	"all-agents": defaultConfig,
+	"user-level": globalConfig,
+}
+
+// resolveExtends decodes a configuration and merges it over the configurations it extends,
+// in the order they are listed. Relative paths in `extends` are resolved against dir, the
+// directory of the file; chain holds the files being resolved, to detect cycles.
+// It returns the merged document and the `extends` list of the configuration itself.
+func resolveExtends(data []byte, dir string, chain []string) (map[string]any, []string, error) {
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+	extends, err := extendsList(doc["extends"])
+	if err != nil {
+		return nil, nil, err
+	}
+	delete(doc, "extends")
+
+	merged := make(map[string]any)
+	for _, ref := range extends {
+		base, err := loadBase(ref, dir, chain)
+		if err != nil {
+			return nil, nil, err
+		}
+		merged = mergeObjects(merged, base, true)
+	}
+	return mergeObjects(merged, doc, true), extends, nil
+}
+
+// loadBase loads a configuration listed in `extends`, together with everything it extends.
+func loadBase(ref, dir string, chain []string) (map[string]any, error) {
+	if name, ok := strings.CutPrefix(ref, presetPrefix); ok {
+		data, ok := presets[name]
+		if !ok {
+			names := make([]string, 0, len(presets))
+			for n := range presets {
+				names = append(names, presetPrefix+n)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown preset %q, expected one of %s", ref, strings.Join(names, ", "))
+		}
+		doc, _, err := resolveExtends(data, dir, chain)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", ref, err)
+		}
+		return doc, nil
+	}
+
+	path, err := ExpandPath(ref)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	for _, p := range chain {
+		if p == path {
+			return nil, fmt.Errorf("config %s extends itself: %s", path, strings.Join(append(chain, path), " -> "))
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read extended config: %w", err)
+	}
+	doc, _, err := resolveExtends(data, filepath.Dir(path), append(chain, path))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// extendsList accepts `extends` as a single string or a list of strings.
+func extendsList(v any) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok || strings.TrimSpace(s) == "" {
+				return nil, fmt.Errorf("extends must list config paths or presets, got %v", item)
+			}
+			list = append(list, strings.TrimSpace(s))
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("extends must be a string or a list of strings")
+}
+
+// mergeObjects merges over into base: objects are merged key by key, null removes a key and
+// any other value, including a list, replaces the one of base. At the top level the agents
+// are merged by name, so an agent of over only needs the fields it changes.
+func mergeObjects(base, over map[string]any, top bool) map[string]any {
+	out := make(map[string]any, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		if top && k == "agents" {
+			out[k] = mergeAgents(out[k], v)
+			continue
+		}
+		if b, ok := out[k].(map[string]any); ok {
+			if o, ok := v.(map[string]any); ok {
+				out[k] = mergeObjects(b, o, false)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// mergeAgents merges the agents of over into those of base with the same name, ignoring case,
+// and appends the others.
+func mergeAgents(base, over any) any {
+	baseList, ok1 := base.([]any)
+	overList, ok2 := over.([]any)
+	if !ok1 || !ok2 {
+		return over
+	}
+	out := append([]any(nil), baseList...)
+	for _, o := range overList {
+		agent, ok := o.(map[string]any)
+		if !ok {
+			out = append(out, o)
+			continue
+		}
+		i := agentIndex(out, agent["name"])
+		if i < 0 {
+			out = append(out, agent)
+			continue
+		}
+		out[i] = mergeObjects(out[i].(map[string]any), agent, false)
+	}
+	return out
+}
+
+func agentIndex(agents []any, name any) int {
+	n, ok := name.(string)
+	if !ok || strings.TrimSpace(n) == "" {
+		return -1
+	}
+	for i, a := range agents {
+		if m, ok := a.(map[string]any); ok {
+			if other, ok := m["name"].(string); ok && strings.EqualFold(strings.TrimSpace(other), strings.TrimSpace(n)) {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/flowmitry/syncai/internal/util"
+)
+
+// FlattenSeparator replaces the directory separator in the stem of a nested rule when it is
+// written for a flattening pattern without `**`, e.g. `backend/api` becomes `backend__api.md`.
+const FlattenSeparator = "__"
+
+// RulesPattern is a parsed rules pattern. Files named Prefix<stem>Suffix in Dir are rules;
+// with Recursive (a `**` segment before the file name) files in subdirectories are too, and
+// their path below Dir becomes part of the stem, e.g. `backend/api`. A pattern without a
+// wildcard names a single file whose stem is the name without its extension.
+//
+// A pattern without `**` only holds nested rules with Flatten, which joins the directories
+// of the stem with FlattenSeparator. Without it the separator is an ordinary part of a name.
+type RulesPattern struct {
+	Raw       string
+	Dir       string
+	Recursive bool
+	Wildcard  bool
+	Flatten   bool
+	Prefix    string
+	Suffix    string
+}
+
+func ParseRulesPattern(pattern string) (RulesPattern, error) {
+	p := RulesPattern{Raw: pattern}
+	clean := path.Clean(filepath.ToSlash(strings.TrimSpace(pattern)))
+	if _, err := path.Match(clean, ""); err != nil {
+		return p, fmt.Errorf("invalid rules pattern %s: %w", pattern, err)
+	}
+	dir, base := path.Split(clean)
+	dir = trimDir(dir)
+	if path.Base(dir) == "**" {
+		p.Recursive = true
+		dir = path.Dir(dir)
+	} else if dir == "**" {
+		p.Recursive = true
+		dir = "."
+	}
+	if dir == "" {
+		dir = "."
+	}
+	if strings.Contains(dir, "*") {
+		return p, fmt.Errorf("rules pattern %s may only use `**` right before the file name and wildcards in the file name", pattern)
+	}
+	switch strings.Count(base, "*") {
+	case 0:
+		if p.Recursive {
+			return p, fmt.Errorf("rules pattern %s needs a wildcard in the file name to use `**`", pattern)
+		}
+		p.Prefix = base
+	case 1:
+		p.Wildcard = true
+		p.Prefix, p.Suffix, _ = strings.Cut(base, "*")
+	default:
+		return p, fmt.Errorf("rules pattern %s may contain at most one wildcard in the file name, e.g. `.cursor/rules/*.mdc`", pattern)
+	}
+	p.Dir = filepath.FromSlash(dir)
+	return p, nil
+}
+
+// Match returns the stem of the file if the pattern matches it.
+func (p RulesPattern) Match(file string) (string, bool) {
+	rel, err := filepath.Rel(p.Dir, filepath.Clean(file))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	sub, name := path.Split(rel)
+	if sub != "" && !p.Recursive {
+		return "", false
+	}
+	if skipsDir(sub) {
+		return "", false
+	}
+	var stem string
+	if p.Wildcard {
+		if len(name) <= len(p.Prefix)+len(p.Suffix) || !strings.HasPrefix(name, p.Prefix) || !strings.HasSuffix(name, p.Suffix) {
+			return "", false
+		}
+		stem = name[len(p.Prefix) : len(name)-len(p.Suffix)]
+	} else {
+		if name != p.Prefix {
+			return "", false
+		}
+		stem = strings.TrimSuffix(name, path.Ext(name))
+	}
+	switch {
+	case p.Recursive:
+		return sub + stem, true
+	case p.Flatten:
+		return strings.ReplaceAll(stem, FlattenSeparator, "/"), true
+	}
+	return stem, true
+}
+
+// Path returns the file of the stem, or "" for a nested stem the pattern cannot hold.
+func (p RulesPattern) Path(stem string) string {
+	name := stem
+	if !p.Recursive && strings.Contains(stem, "/") {
+		if !p.Flatten {
+			return ""
+		}
+		name = strings.ReplaceAll(stem, "/", FlattenSeparator)
+	}
+	if p.Wildcard {
+		name = p.Prefix + name + p.Suffix
+	} else {
+		name += path.Ext(p.Prefix)
+	}
+	return filepath.Join(p.Dir, filepath.FromSlash(name))
+}
+
+// Glob returns the existing files matched by the pattern, with Dir resolved against root.
+func (p RulesPattern) Glob(root string) ([]string, error) {
+	if !p.Recursive {
+		name := p.Prefix
+		if p.Wildcard {
+			name += "*" + p.Suffix
+		}
+		matches, err := filepath.Glob(util.Resolve(root, filepath.Join(p.Dir, name)))
+		for i, m := range matches {
+			matches[i] = filepath.Join(p.Dir, filepath.Base(m))
+		}
+		return matches, err
+	}
+	files := make([]string, 0)
+	err := util.WalkFiles(root, p.Dir, func(file string) error {
+		if _, ok := p.Match(file); ok {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// ContextPattern is a context path. With Nested (a `**` segment before the file name, as in
+// `**/AGENTS.md`) it matches the file in Dir and in every directory below it, and the
+// directory relative to Dir is the stem: `services/api` for `services/api/AGENTS.md`, ""
+// for Dir itself.
+type ContextPattern struct {
+	Raw    string
+	Dir    string
+	Name   string
+	Nested bool
+}
+
+func ParseContextPattern(pattern string) (ContextPattern, error) {
+	p := ContextPattern{Raw: pattern}
+	clean := path.Clean(filepath.ToSlash(strings.TrimSpace(pattern)))
+	dir, name := path.Split(clean)
+	dir = trimDir(dir)
+	if dir == "**" {
+		p.Nested = true
+		dir = "."
+	} else if path.Base(dir) == "**" {
+		p.Nested = true
+		dir = path.Dir(dir)
+	}
+	if dir == "" {
+		dir = "."
+	}
+	if strings.ContainsAny(dir, "*?[") || strings.ContainsAny(name, "*?[") {
+		return p, fmt.Errorf("context path %s may only contain `**` right before the file name", pattern)
+	}
+	p.Dir = filepath.FromSlash(dir)
+	p.Name = name
+	return p, nil
+}
+
+// Match returns the stem of the file if the pattern matches it.
+func (p ContextPattern) Match(file string) (string, bool) {
+	if !p.Nested {
+		return "", filepath.Clean(file) == filepath.Join(p.Dir, p.Name)
+	}
+	rel, err := filepath.Rel(p.Dir, filepath.Clean(file))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	sub, name := path.Split(filepath.ToSlash(rel))
+	if name != p.Name || skipsDir(sub) {
+		return "", false
+	}
+	return strings.TrimSuffix(sub, "/"), true
+}
+
+// Path returns the file of the stem. A pattern that is not nested only has the file of "".
+func (p ContextPattern) Path(stem string) (string, bool) {
+	if stem != "" && !p.Nested {
+		return "", false
+	}
+	return filepath.Join(p.Dir, filepath.FromSlash(stem), p.Name), true
+}
+
+// Glob returns the existing files matched by a nested pattern, with Dir resolved against root.
+func (p ContextPattern) Glob(root string) ([]string, error) {
+	files := make([]string, 0)
+	err := util.WalkFiles(root, p.Dir, func(file string) error {
+		if _, ok := p.Match(file); ok {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// trimDir removes the trailing slash path.Split leaves on a directory, keeping the root of
+// an absolute path such as `/AGENTS.md`.
+func trimDir(dir string) string {
+	if dir == "/" {
+		return dir
+	}
+	return strings.TrimSuffix(dir, "/")
+}
+
+// skipsDir reports whether a relative directory lies in a directory recursive patterns skip.
+func skipsDir(dir string) bool {
+	for _, d := range strings.Split(strings.TrimSuffix(dir, "/"), "/") {
+		if util.SkipDir(d) {
+			return true
+		}
+	}
+	return false
+}
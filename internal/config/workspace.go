@@ -0,0 +1,82 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Workspace lists the projects synced by a single SyncAI process.
+type Workspace struct {
+	Projects []Project `json:"projects"`
+}
+
+// Project is one entry of a workspace: a configuration file and, optionally, the working
+// directory that overrides the one of the configuration.
+type Project struct {
+	Name    string `json:"name"`
+	Config  string `json:"config"`
+	WorkDir string `json:"workdir"`
+}
+
+// LoadWorkspace reads a workspace file. Relative paths of its projects are resolved against
+// the directory of the file, and a project without a name is named after that of its
+// working directory or configuration.
+func LoadWorkspace(path string) (Workspace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Workspace{}, fmt.Errorf("read workspace: %w", err)
+	}
+	var ws Workspace
+	if err := json.Unmarshal(data, &ws); err != nil {
+		return Workspace{}, fmt.Errorf("parse workspace: %w", err)
+	}
+	if len(ws.Projects) == 0 {
+		return Workspace{}, fmt.Errorf("workspace has no projects defined")
+	}
+
+	base := filepath.Dir(path)
+	resolve := func(p string) (string, error) {
+		p, err := ExpandPath(strings.TrimSpace(p))
+		if err != nil || p == "" || filepath.IsAbs(p) {
+			return p, err
+		}
+		return filepath.Join(base, p), nil
+	}
+	var errs []error
+	names := make(map[string]bool)
+	for i := range ws.Projects {
+		p := &ws.Projects[i]
+		if p.Config, err = resolve(p.Config); err != nil {
+			errs = append(errs, err)
+		}
+		if p.WorkDir, err = resolve(p.WorkDir); err != nil {
+			errs = append(errs, err)
+		}
+		if p.Config == "" {
+			errs = append(errs, fmt.Errorf("project #%d has no config", i+1))
+			continue
+		}
+		if p.Name = strings.TrimSpace(p.Name); p.Name == "" {
+			dir := p.WorkDir
+			if dir == "" {
+				dir = filepath.Dir(p.Config)
+			}
+			if abs, err := filepath.Abs(dir); err == nil {
+				dir = abs
+			}
+			p.Name = filepath.Base(dir)
+		}
+		if names[p.Name] {
+			errs = append(errs, fmt.Errorf("project %q is defined more than once", p.Name))
+		}
+		names[p.Name] = true
+	}
+	if len(errs) > 0 {
+		return Workspace{}, fmt.Errorf("invalid workspace: %w", errors.Join(errs...))
+	}
+	return ws, nil
+}
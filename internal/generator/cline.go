@@ -0,0 +1,47 @@
+package generator
+
+import (
+	"github.com/flowmitry/syncai/internal/model"
+	"strings"
+)
+
+// ClineRulesGenerator writes Cline rules, which are always active unless a `paths` list
+// in the front matter limits them to matching files. Cline cannot express agent-requested
+// or manual rules, so a rule without paths leaves the activation to the other copies.
+type ClineRulesGenerator struct{}
+
+func (g ClineRulesGenerator) ParseRules(metadata model.DocumentMetadata) (model.RulesMetadata, error) {
+	var rules model.RulesMetadata
+	for _, e := range metadata.Entries {
+		switch strings.ToLower(e.Key) {
+		case "paths":
+			rules.Globs = ParseGlobs(e.Strings())
+			rules.Activation = globsActivation(rules.Globs)
+		default:
+			rules.ExtraFields = append(rules.ExtraFields, e)
+		}
+	}
+	return rules, nil
+}
+
+func (g ClineRulesGenerator) GenerateRules(metadata model.RulesMetadata, content []byte) ([]byte, error) {
+	var extra strings.Builder
+	writeExtraFields(&extra, metadata)
+	globs := metadata.Mode() == model.ActivationGlobs
+	if !globs && extra.Len() == 0 {
+		return content, nil
+	}
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	if globs {
+		sb.WriteString("paths:\n")
+		for _, glob := range metadata.Globs {
+			sb.WriteString("  - ")
+			sb.WriteString(quoteIfNeeded(glob))
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString(extra.String())
+	sb.WriteString("---\n")
+	return append([]byte(sb.String()), content...), nil
+}
@@ -1,42 +1,61 @@
 package generator
 
 import (
-	"sort"
+	"github.com/flowmitry/syncai/internal/model"
 	"strings"
-	"syncai/internal/model"
 )
 
+// CopilotRulesGenerator writes Copilot instructions. Instructions without `applyTo` are not
+// applied automatically; a description lets the agent pick them when relevant.
 type CopilotRulesGenerator struct{}
 
-func (g CopilotRulesGenerator) GenerateRules(metadata model.RulesMetadata, content []byte) []byte {
+func (g CopilotRulesGenerator) ParseRules(metadata model.DocumentMetadata) (model.RulesMetadata, error) {
+	var rules model.RulesMetadata
+	for _, e := range metadata.Entries {
+		switch strings.ToLower(e.Key) {
+		case "description":
+			rules.Description, _ = e.String()
+		case "applyto":
+			rules.Globs = ParseGlobs(e.Strings())
+		default:
+			rules.ExtraFields = append(rules.ExtraFields, e)
+		}
+	}
+	switch {
+	case len(rules.Globs) > 0:
+		rules.Activation = globsActivation(rules.Globs)
+	case rules.Description != "":
+		rules.Activation = model.ActivationRequested
+	default:
+		rules.Activation = model.ActivationManual
+	}
+	return rules, nil
+}
+
+func (g CopilotRulesGenerator) GenerateRules(metadata model.RulesMetadata, content []byte) ([]byte, error) {
+	mode := metadata.Mode()
+	description := metadata.Description
+	if mode == model.ActivationManual {
+		// A description would turn a manual instruction into an agent-requested one
+		description = ""
+	}
+
 	var sb strings.Builder
 	sb.WriteString("---\n")
 	sb.WriteString("description: ")
-	sb.WriteString(quoteIfNeeded(metadata.Description))
-	sb.WriteString("\n")
-	sb.WriteString("applyTo: ")
-	if metadata.Globs == "" {
-		sb.WriteString(quoteIfNeeded("**"))
-	} else {
-		sb.WriteString(quoteIfNeeded(metadata.Globs))
-	}
+	sb.WriteString(quoteIfNeeded(description))
 	sb.WriteString("\n")
-	if len(metadata.ExtraFields) > 0 {
-		keys := make([]string, 0, len(metadata.ExtraFields))
-		for k := range metadata.ExtraFields {
-			if isReservedField(k) {
-				continue
-			}
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			sb.WriteString(k)
-			sb.WriteString(": ")
-			sb.WriteString(quoteIfNeeded(metadata.ExtraFields[k]))
-			sb.WriteString("\n")
-		}
+	switch mode {
+	case model.ActivationAlways:
+		sb.WriteString("applyTo: ")
+		sb.WriteString(quoteIfNeeded(model.GlobAll))
+		sb.WriteString("\n")
+	case model.ActivationGlobs:
+		sb.WriteString("applyTo: ")
+		sb.WriteString(quoteIfNeeded(strings.Join(metadata.Globs, ",")))
+		sb.WriteString("\n")
 	}
+	writeExtraFields(&sb, metadata)
 	sb.WriteString("---\n")
-	return append([]byte(sb.String()), content...)
+	return append([]byte(sb.String()), content...), nil
 }
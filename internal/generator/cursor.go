@@ -2,40 +2,66 @@ package generator
 
 import (
 	"fmt"
-	"sort"
+	"github.com/flowmitry/syncai/internal/model"
 	"strings"
-	"syncai/internal/model"
 )
 
+// CursorRulesGenerator writes Cursor rules. Cursor derives the rule type from the fields:
+// alwaysApply for "Always", globs for "Auto Attached", a description alone for
+// "Agent Requested" and neither for "Manual".
 type CursorRulesGenerator struct{}
 
-func (g CursorRulesGenerator) GenerateRules(metadata model.RulesMetadata, content []byte) []byte {
+func (g CursorRulesGenerator) ParseRules(metadata model.DocumentMetadata) (model.RulesMetadata, error) {
+	var rules model.RulesMetadata
+	alwaysApply := false
+	for _, e := range metadata.Entries {
+		switch strings.ToLower(e.Key) {
+		case "description":
+			rules.Description, _ = e.String()
+		case "globs":
+			rules.Globs = ParseGlobs(e.Strings())
+		case "alwaysapply":
+			alwaysApply = isTruthy(e)
+		default:
+			rules.ExtraFields = append(rules.ExtraFields, e)
+		}
+	}
+	switch {
+	case alwaysApply:
+		rules.Activation = model.ActivationAlways
+	case len(rules.Globs) > 0:
+		rules.Activation = globsActivation(rules.Globs)
+	case rules.Description != "":
+		rules.Activation = model.ActivationRequested
+	default:
+		rules.Activation = model.ActivationManual
+	}
+	return rules, nil
+}
+
+func (g CursorRulesGenerator) GenerateRules(metadata model.RulesMetadata, content []byte) ([]byte, error) {
+	mode := metadata.Mode()
+	description := metadata.Description
+	if mode == model.ActivationManual {
+		// A description would turn a manual rule into an agent-requested one
+		description = ""
+	}
+	globs := ""
+	if mode == model.ActivationGlobs {
+		// Cursor expects an unquoted, comma-separated list
+		globs = strings.Join(metadata.Globs, ",")
+	}
+
 	var sb strings.Builder
 	sb.WriteString("---\n")
 	sb.WriteString("description: ")
-	sb.WriteString(quoteIfNeeded(metadata.Description))
+	sb.WriteString(quoteIfNeeded(description))
 	sb.WriteString("\n")
-	sb.WriteString(fmt.Sprintf("alwaysApply: %t\n", metadata.IsAlwaysApply()))
+	sb.WriteString(fmt.Sprintf("alwaysApply: %t\n", mode == model.ActivationAlways))
 	sb.WriteString("globs: ")
-	sb.WriteString(metadata.Globs)
+	sb.WriteString(globs)
 	sb.WriteString("\n")
-	// Extra fields
-	if len(metadata.ExtraFields) > 0 {
-		keys := make([]string, 0, len(metadata.ExtraFields))
-		for k := range metadata.ExtraFields {
-			if isReservedField(k) {
-				continue
-			}
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			sb.WriteString(k)
-			sb.WriteString(": ")
-			sb.WriteString(quoteIfNeeded(metadata.ExtraFields[k]))
-			sb.WriteString("\n")
-		}
-	}
+	writeExtraFields(&sb, metadata)
 	sb.WriteString("---\n")
-	return append([]byte(sb.String()), content...)
+	return append([]byte(sb.String()), content...), nil
 }
@@ -0,0 +1,189 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/flowmitry/syncai/internal/model"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExternalRulesGenerator delegates parsing and rendering to a command. Every call runs the
+// command once with a JSON request on stdin and reads a JSON response from stdout:
+//
+//	{"mode": "parse", "agent": "acme", "metadata": [{"key": "scope", "value": "backend"}]}
+//	-> {"rules": {"activation": "globs", "globs": ["**/*.go"], "extra": [...]}}
+//
+//	{"mode": "generate", "agent": "acme", "rules": {...}, "content": "rule body\n"}
+//	-> {"content": "---\nscope: backend\n---\nrule body\n"}
+//
+// A response may set "error" instead; it is reported together with a non-zero exit status.
+//
+// The command runs in Dir, the project root, and a relative command path such as
+// `tools/acme-rules.py` is resolved against it; a bare name is looked up in PATH.
+type ExternalRulesGenerator struct {
+	Agent   string
+	Command []string
+	Timeout time.Duration
+	Dir     string
+
+	// responses holds the successful responses by request within a sync, and ctx stops the
+	// commands of that sync; see Generators.Session.
+	responses map[string]externalResponse
+	ctx       context.Context
+}
+
+const (
+	externalModeParse    = "parse"
+	externalModeGenerate = "generate"
+)
+
+type externalField struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+type externalRules struct {
+	Activation  model.Activation `json:"activation,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Globs       []string         `json:"globs,omitempty"`
+	Extra       []externalField  `json:"extra,omitempty"`
+}
+
+type externalRequest struct {
+	Mode     string          `json:"mode"`
+	Agent    string          `json:"agent"`
+	Metadata []externalField `json:"metadata,omitempty"`
+	Rules    *externalRules  `json:"rules,omitempty"`
+	Content  *string         `json:"content,omitempty"`
+}
+
+type externalResponse struct {
+	Rules   externalRules `json:"rules"`
+	Content *string       `json:"content"`
+	Error   string        `json:"error"`
+}
+
+func (g ExternalRulesGenerator) ParseRules(metadata model.DocumentMetadata) (model.RulesMetadata, error) {
+	req := externalRequest{Mode: externalModeParse, Agent: g.Agent, Metadata: make([]externalField, 0, len(metadata.Entries))}
+	for _, e := range metadata.Entries {
+		req.Metadata = append(req.Metadata, externalField{Key: e.Key, Value: e.Value})
+	}
+	resp, err := g.call(req)
+	if err != nil {
+		return model.RulesMetadata{}, err
+	}
+	rules := model.RulesMetadata{
+		Activation:  resp.Rules.Activation,
+		Description: resp.Rules.Description,
+		Globs:       ParseGlobs(resp.Rules.Globs),
+	}
+	for _, f := range resp.Rules.Extra {
+		// Keep the original node when the command passes an entry through unchanged
+		if e, ok := metadata.Get(f.Key); ok && equalJSON(e.Value, f.Value) {
+			rules.ExtraFields = append(rules.ExtraFields, e)
+			continue
+		}
+		rules.ExtraFields = append(rules.ExtraFields, model.MetadataEntry{Key: f.Key, Value: f.Value})
+	}
+	return rules, nil
+}
+
+func (g ExternalRulesGenerator) GenerateRules(metadata model.RulesMetadata, content []byte) ([]byte, error) {
+	body := string(content)
+	rules := externalRules{
+		Activation:  metadata.Mode(),
+		Description: metadata.Description,
+		Globs:       metadata.Globs,
+	}
+	for _, e := range metadata.ExtraFields {
+		rules.Extra = append(rules.Extra, externalField{Key: e.Key, Value: e.Value})
+	}
+	resp, err := g.call(externalRequest{Mode: externalModeGenerate, Agent: g.Agent, Rules: &rules, Content: &body})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Content == nil {
+		return nil, fmt.Errorf("generator %s returned no content", g.name())
+	}
+	return []byte(*resp.Content), nil
+}
+
+func (g ExternalRulesGenerator) call(req externalRequest) (externalResponse, error) {
+	var resp externalResponse
+	if len(g.Command) == 0 {
+		return resp, fmt.Errorf("no generator command configured for agent %s", g.Agent)
+	}
+	in, err := json.Marshal(req)
+	if err != nil {
+		return resp, fmt.Errorf("encode %s request: %w", req.Mode, err)
+	}
+	if cached, ok := g.responses[string(in)]; ok {
+		return cached, nil
+	}
+
+	timeout := g.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	parent := g.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, g.command(), g.Command[1:]...)
+	cmd.Dir = g.Dir
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil && runErr == nil {
+		return resp, fmt.Errorf("generator %s: invalid %s response: %w", g.name(), req.Mode, err)
+	}
+	if resp.Error != "" {
+		return resp, fmt.Errorf("generator %s: %s", g.name(), resp.Error)
+	}
+	if runErr != nil {
+		if err := parent.Err(); err != nil {
+			return resp, fmt.Errorf("generator %s: %w", g.name(), err)
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return resp, fmt.Errorf("generator %s timed out after %s", g.name(), timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return resp, fmt.Errorf("generator %s: %w: %s", g.name(), runErr, msg)
+		}
+		return resp, fmt.Errorf("generator %s: %w", g.name(), runErr)
+	}
+	if g.responses != nil {
+		g.responses[string(in)] = resp
+	}
+	return resp, nil
+}
+
+func (g ExternalRulesGenerator) command() string {
+	name := g.Command[0]
+	if g.Dir == "" || filepath.IsAbs(name) || !strings.ContainsRune(filepath.ToSlash(name), '/') {
+		return name
+	}
+	return filepath.Join(g.Dir, name)
+}
+
+func (g ExternalRulesGenerator) name() string {
+	return strings.Join(g.Command, " ")
+}
+
+// equalJSON compares two values as they are seen by the command.
+func equalJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
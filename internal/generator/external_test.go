@@ -0,0 +1,25 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/model"
+)
+
+func TestExternalGeneratorStopsWithSession(t *testing.T) {
+	gens := Generators{"acme": ExternalRulesGenerator{Agent: "acme", Command: []string{"sleep", "5"}, Timeout: time.Minute}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := gens.Session(ctx).Get("acme").ParseRules(model.DocumentMetadata{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error is %v, want it to wrap context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("generator ran for %s after the sync was cancelled", elapsed)
+	}
+}
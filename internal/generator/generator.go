@@ -1,12 +1,46 @@
 package generator
 
 import (
+	"context"
+	"fmt"
+	"github.com/flowmitry/syncai/internal/model"
 	"strings"
-	"syncai/internal/model"
 )
 
+// RulesGenerator converts rule metadata from and to one agent's front matter format.
 type RulesGenerator interface {
-	GenerateRules(metadata model.RulesMetadata, content []byte) []byte
+	// ParseRules reads the rule metadata expressed in this agent's front matter. Entries the
+	// format does not own are returned as ExtraFields.
+	ParseRules(metadata model.DocumentMetadata) (model.RulesMetadata, error)
+	GenerateRules(metadata model.RulesMetadata, content []byte) ([]byte, error)
+}
+
+// Generators maps agent names to the generators configured for them. Agents without an
+// entry use the built-in generator returned by GetRulesGenerator.
+type Generators map[string]RulesGenerator
+
+func (g Generators) Get(agentName string) RulesGenerator {
+	if gen, ok := g[strings.ToLower(agentName)]; ok {
+		return gen
+	}
+	return GetRulesGenerator(agentName)
+}
+
+// Session returns the generators to use for one sync. Merging metadata parses and renders
+// the same copies for every destination, so an external command is run once per distinct
+// request and its response reused until the sync is done. Cancelling ctx stops a running
+// command.
+func (g Generators) Session(ctx context.Context) Generators {
+	out := make(Generators, len(g))
+	for name, gen := range g {
+		if ext, ok := gen.(ExternalRulesGenerator); ok {
+			ext.responses = make(map[string]externalResponse)
+			ext.ctx = ctx
+			gen = ext
+		}
+		out[name] = gen
+	}
+	return out
 }
 
 func GetRulesGenerator(agentName string) RulesGenerator {
@@ -15,42 +49,28 @@ func GetRulesGenerator(agentName string) RulesGenerator {
 		return CursorRulesGenerator{}
 	case model.AgentCopilot:
 		return CopilotRulesGenerator{}
+	case model.AgentCline:
+		return ClineRulesGenerator{}
+	case model.AgentKiro:
+		return KiroRulesGenerator{}
 	default:
 		return OtherRulesGenerator{}
 	}
 }
 
-func ExtractRulesMetadata(s *model.DocumentStack) model.RulesMetadata {
-	metadata := model.RulesMetadata{
-		ExtraFields: make(map[string]string),
+// ExtractRulesMetadata combines the rule metadata of every document in the stack, each parsed
+// with the generator of the agent it belongs to, according to the policy.
+func ExtractRulesMetadata(s *model.DocumentStack, policy model.MergePolicy, gens Generators) (model.RulesMetadata, error) {
+	if policy == model.MergeNewestValue {
+		return mergeNewestValue(s, gens)
 	}
-	for _, d := range s.Documents {
-		for k, v := range d.Metadata.Raw {
-			keyName := strings.ToLower(k)
-			switch keyName {
-			case "description":
-				if strings.TrimSpace(v) != "" {
-					metadata.Description = v
-				}
-			case "globs":
-				if strings.TrimSpace(v) != "" {
-					metadata.Globs = v
-				}
-			case "applyto":
-				// Copilot uses `applyTo` for globs
-				if strings.TrimSpace(v) != "" {
-					metadata.Globs = v
-				}
-			case "alwaysapply":
-				// Only set to always-apply when the value is truthy
-				vv := strings.ToLower(strings.TrimSpace(v))
-				if vv == "true" || vv == "1" || vv == "yes" || vv == "on" {
-					metadata.Globs = "**"
-				}
-			default:
-				metadata.ExtraFields[k] = v
-			}
-		}
+	return mergeChanged(s, gens)
+}
+
+func parseDocument(d model.Document, gens Generators) (model.RulesMetadata, error) {
+	rules, err := gens.Get(d.Agent).ParseRules(d.Metadata)
+	if err != nil {
+		return rules, fmt.Errorf("parse rules of %s: %w", d.FileInfo.Path, err)
 	}
-	return metadata
+	return rules, nil
 }
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flowmitry/syncai/internal/model"
+	"github.com/flowmitry/syncai/internal/util"
+)
+
+// TestExtraFieldsRoundTrip checks that every built-in format keeps front matter keys it
+// does not own, whatever the activation of the rule.
+func TestExtraFieldsRoundTrip(t *testing.T) {
+	gens := map[string]RulesGenerator{
+		"cursor":  CursorRulesGenerator{},
+		"copilot": CopilotRulesGenerator{},
+		"cline":   ClineRulesGenerator{},
+		"kiro":    KiroRulesGenerator{},
+	}
+	activations := map[string]model.RulesMetadata{
+		"always": {Activation: model.ActivationAlways},
+		"globs":  {Activation: model.ActivationGlobs, Globs: []string{"**/*.go"}},
+	}
+	source, _ := util.Parse([]byte("---\nowner: platform # team\nreviewers:\n  - alice\n---\n"))
+	for name, gen := range gens {
+		for mode, rules := range activations {
+			rules.ExtraFields = source.Entries
+			data, err := gen.GenerateRules(rules, []byte("Use gofmt.\n"))
+			if err != nil {
+				t.Fatalf("%s/%s: %v", name, mode, err)
+			}
+			metadata, body := util.Parse(data)
+			if string(body) != "Use gofmt.\n" {
+				t.Errorf("%s/%s: body is %q", name, mode, body)
+			}
+			parsed, err := gen.ParseRules(metadata)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", name, mode, err)
+			}
+			if parsed.Mode() != rules.Mode() {
+				t.Errorf("%s/%s: activation is %v after a round trip", name, mode, parsed.Mode())
+			}
+			var keys []string
+			for _, e := range parsed.ExtraFields {
+				keys = append(keys, e.Key)
+			}
+			if got := strings.Join(keys, ","); got != "owner,reviewers" {
+				t.Errorf("%s/%s: extra fields are %q, want owner,reviewers in\n%s", name, mode, got, data)
+			}
+			if !strings.Contains(string(data), "owner: platform # team\n") {
+				t.Errorf("%s/%s: comment of an extra field was lost in\n%s", name, mode, data)
+			}
+		}
+	}
+}
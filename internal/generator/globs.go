@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"strings"
+)
+
+// ParseGlobs turns glob values as written by any agent into a normalized list:
+// comma-separated values are split, braces are expanded, quotes and leading "./" or "/"
+// are removed, and patterns without a directory get a "**/" prefix so they match at any depth.
+// A pattern matching everything is normalized to "**".
+func ParseGlobs(values []string) []string {
+	result := make([]string, 0, len(values))
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, part := range splitTopLevel(value) {
+			for _, glob := range expandBraces(part) {
+				glob = normalizeGlob(glob)
+				if glob == "" || seen[glob] {
+					continue
+				}
+				seen[glob] = true
+				result = append(result, glob)
+			}
+		}
+	}
+	return result
+}
+
+// JoinGlobs combines several globs into a single pattern using brace alternation,
+// for formats that accept only one pattern.
+func JoinGlobs(globs []string) string {
+	switch len(globs) {
+	case 0:
+		return ""
+	case 1:
+		return globs[0]
+	default:
+		return "{" + strings.Join(globs, ",") + "}"
+	}
+}
+
+func normalizeGlob(glob string) string {
+	glob = strings.TrimSpace(glob)
+	glob = strings.Trim(glob, `"'`)
+	glob = strings.TrimSpace(glob)
+	for strings.HasPrefix(glob, "./") {
+		glob = strings.TrimPrefix(glob, "./")
+	}
+	glob = strings.TrimPrefix(glob, "/")
+	switch glob {
+	case "":
+		return ""
+	case "*", "**", "**/*":
+		return "**"
+	}
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	}
+	return glob
+}
+
+// splitTopLevel splits a comma-separated list, ignoring commas inside braces.
+func splitTopLevel(value string) []string {
+	parts := make([]string, 0, 1)
+	depth := 0
+	start := 0
+	for i, r := range value {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// expandBraces expands the first brace group and recurses, e.g. "*.{ts,tsx}" -> "*.ts", "*.tsx".
+func expandBraces(glob string) []string {
+	open := strings.Index(glob, "{")
+	if open < 0 {
+		return []string{glob}
+	}
+	depth := 0
+	for i := open; i < len(glob); i++ {
+		switch glob[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				prefix, suffix := glob[:open], glob[i+1:]
+				result := make([]string, 0)
+				for _, alt := range splitTopLevel(glob[open+1 : i]) {
+					result = append(result, expandBraces(prefix+alt+suffix)...)
+				}
+				return result
+			}
+		}
+	}
+	// Unbalanced brace; keep the pattern as written
+	return []string{glob}
+}
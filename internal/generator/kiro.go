@@ -0,0 +1,54 @@
+package generator
+
+import (
+	"github.com/flowmitry/syncai/internal/model"
+	"strings"
+)
+
+// KiroRulesGenerator writes Kiro steering files. The `inclusion` mode is always, fileMatch
+// or manual; agent-requested rules are written as manual. A fileMatch rule takes a single
+// `fileMatchPattern`, so several globs are combined into one brace pattern.
+type KiroRulesGenerator struct{}
+
+func (g KiroRulesGenerator) ParseRules(metadata model.DocumentMetadata) (model.RulesMetadata, error) {
+	var rules model.RulesMetadata
+	inclusion := ""
+	for _, e := range metadata.Entries {
+		switch strings.ToLower(e.Key) {
+		case "inclusion":
+			inclusion, _ = e.String()
+		case "filematchpattern":
+			rules.Globs = ParseGlobs(e.Strings())
+		default:
+			rules.ExtraFields = append(rules.ExtraFields, e)
+		}
+	}
+	switch strings.ToLower(inclusion) {
+	case "always":
+		rules.Activation = model.ActivationAlways
+	case "filematch":
+		rules.Activation = globsActivation(rules.Globs)
+	case "manual":
+		rules.Activation = model.ActivationManual
+	}
+	return rules, nil
+}
+
+func (g KiroRulesGenerator) GenerateRules(metadata model.RulesMetadata, content []byte) ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	switch metadata.Mode() {
+	case model.ActivationGlobs:
+		sb.WriteString("inclusion: fileMatch\n")
+		sb.WriteString("fileMatchPattern: ")
+		sb.WriteString(quoteIfNeeded(JoinGlobs(metadata.Globs)))
+		sb.WriteString("\n")
+	case model.ActivationRequested, model.ActivationManual:
+		sb.WriteString("inclusion: manual\n")
+	default:
+		sb.WriteString("inclusion: always\n")
+	}
+	writeExtraFields(&sb, metadata)
+	sb.WriteString("---\n")
+	return append([]byte(sb.String()), content...), nil
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"github.com/flowmitry/syncai/internal/model"
+	"github.com/flowmitry/syncai/internal/util"
+	"slices"
+	"strings"
+)
+
+// mergeChanged takes the changed document as authoritative. For each field, an older copy's
+// value is only used when the changed document's format renders it exactly like the changed
+// document, i.e. when the format cannot tell the two values apart (a description in a format
+// without descriptions, an agent-requested rule in a format that only knows manual ones).
+// Older copies are consulted newest first.
+func mergeChanged(s *model.DocumentStack, gens Generators) (model.RulesMetadata, error) {
+	docs := make([]model.Document, 0, len(s.Documents))
+	var changed *model.Document
+	for i := range s.Documents {
+		if s.Documents[i].FileInfo.Path == s.ChangedPath && changed == nil {
+			changed = &s.Documents[i]
+			continue
+		}
+		docs = append(docs, s.Documents[i])
+	}
+	if changed == nil {
+		return mergeNewestValue(s, gens)
+	}
+	slices.SortStableFunc(docs, func(a, b model.Document) int {
+		return b.FileInfo.ModTime.Compare(a.FileInfo.ModTime)
+	})
+
+	gen := gens.Get(changed.Agent)
+	base, err := parseDocument(*changed, gens)
+	if err != nil {
+		return base, err
+	}
+	result := base
+	result.ExtraFields = append([]model.MetadataEntry(nil), base.ExtraFields...)
+
+	var activationDone, descriptionDone, globsDone bool
+	extraKeys := make(map[string]bool)
+	for _, e := range base.ExtraFields {
+		extraKeys[strings.ToLower(e.Key)] = true
+	}
+	for _, d := range docs {
+		older, err := parseDocument(d, gens)
+		if err != nil {
+			return result, err
+		}
+		rendered, err := roundTrip(gen, older)
+		if err != nil {
+			return result, err
+		}
+
+		if !activationDone && older.Activation != model.ActivationUnknown {
+			if older.Activation == base.Activation {
+				activationDone = true
+			} else if rendered.Activation == base.Activation {
+				result.Activation = older.Activation
+				activationDone = true
+			}
+		}
+		if !descriptionDone && older.Description != "" {
+			if older.Description == base.Description {
+				descriptionDone = true
+			} else if rendered.Description == base.Description {
+				result.Description = older.Description
+				descriptionDone = true
+			}
+		}
+		if !globsDone && len(older.Globs) > 0 {
+			if slices.Equal(older.Globs, base.Globs) {
+				globsDone = true
+			} else if slices.Equal(rendered.Globs, base.Globs) {
+				result.Globs = older.Globs
+				globsDone = true
+			}
+		}
+
+		// Extra keys the changed format would have kept were removed on purpose
+		renderedKeys := make(map[string]bool)
+		for _, e := range rendered.ExtraFields {
+			renderedKeys[strings.ToLower(e.Key)] = true
+		}
+		for _, e := range older.ExtraFields {
+			key := strings.ToLower(e.Key)
+			if extraKeys[key] || renderedKeys[key] {
+				continue
+			}
+			extraKeys[key] = true
+			result.ExtraFields = append(result.ExtraFields, e)
+		}
+	}
+	return result, nil
+}
+
+// roundTrip returns the metadata as the generator's format would keep it.
+func roundTrip(gen RulesGenerator, metadata model.RulesMetadata) (model.RulesMetadata, error) {
+	data, err := gen.GenerateRules(metadata, nil)
+	if err != nil {
+		return model.RulesMetadata{}, err
+	}
+	parsed, _ := util.Parse(data)
+	return gen.ParseRules(parsed)
+}
+
+// mergeNewestValue goes through the documents oldest first and keeps, for every field,
+// the last non-empty value.
+func mergeNewestValue(s *model.DocumentStack, gens Generators) (model.RulesMetadata, error) {
+	metadata := model.RulesMetadata{
+		ExtraFields: make([]model.MetadataEntry, 0),
+	}
+	extraIndex := make(map[string]int)
+	for _, d := range s.Documents {
+		parsed, err := parseDocument(d, gens)
+		if err != nil {
+			return metadata, err
+		}
+		if parsed.Activation != model.ActivationUnknown {
+			metadata.Activation = parsed.Activation
+		}
+		if parsed.Description != "" {
+			metadata.Description = parsed.Description
+		}
+		if len(parsed.Globs) > 0 {
+			metadata.Globs = parsed.Globs
+		}
+		for _, e := range parsed.ExtraFields {
+			// Newer documents override older ones, keeping the position of the first occurrence
+			keyName := strings.ToLower(e.Key)
+			if i, ok := extraIndex[keyName]; ok {
+				metadata.ExtraFields[i] = e
+			} else {
+				extraIndex[keyName] = len(metadata.ExtraFields)
+				metadata.ExtraFields = append(metadata.ExtraFields, e)
+			}
+		}
+	}
+	return metadata, nil
+}
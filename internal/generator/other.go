@@ -1,11 +1,41 @@
 package generator
 
 import (
-	"syncai/internal/model"
+	"github.com/flowmitry/syncai/internal/model"
+	"strings"
 )
 
 type OtherRulesGenerator struct{}
 
-func (g OtherRulesGenerator) GenerateRules(metadata model.RulesMetadata, content []byte) []byte {
-	return content
+// ParseRules recognizes the keys of every known format, since the agent's own format is unknown.
+func (g OtherRulesGenerator) ParseRules(metadata model.DocumentMetadata) (model.RulesMetadata, error) {
+	var rules model.RulesMetadata
+	for _, e := range metadata.Entries {
+		switch strings.ToLower(e.Key) {
+		case "description":
+			rules.Description, _ = e.String()
+		case "globs", "applyto", "paths", "filematchpattern":
+			if globs := ParseGlobs(e.Strings()); len(globs) > 0 {
+				rules.Globs = globs
+			}
+		case "alwaysapply":
+			if isTruthy(e) {
+				rules.Activation = model.ActivationAlways
+			}
+		case "inclusion":
+			switch v, _ := e.String(); strings.ToLower(v) {
+			case "always":
+				rules.Activation = model.ActivationAlways
+			case "manual":
+				rules.Activation = model.ActivationManual
+			}
+		default:
+			rules.ExtraFields = append(rules.ExtraFields, e)
+		}
+	}
+	return rules, nil
+}
+
+func (g OtherRulesGenerator) GenerateRules(metadata model.RulesMetadata, content []byte) ([]byte, error) {
+	return content, nil
 }
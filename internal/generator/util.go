@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"github.com/flowmitry/syncai/internal/model"
 	"strconv"
 	"strings"
 )
@@ -8,8 +9,44 @@ import (
 const yamlSpecialChars = " \":{}[]#&*!|>'%@`"
 
 func isReservedField(field string) bool {
-	fl := strings.ToLower(field)
-	return fl == "description" || fl == "globs" || fl == "applyto" || fl == "alwaysapply"
+	switch strings.ToLower(field) {
+	case "description", "globs", "applyto", "alwaysapply", "paths", "inclusion", "filematchpattern":
+		return true
+	default:
+		return false
+	}
+}
+
+// globsActivation is the activation of a rule limited to the given globs.
+func globsActivation(globs []string) model.Activation {
+	for _, g := range globs {
+		if g == model.GlobAll {
+			return model.ActivationAlways
+		}
+	}
+	return model.ActivationGlobs
+}
+
+// isTruthy reports whether a front matter entry holds a true-like value.
+func isTruthy(e model.MetadataEntry) bool {
+	v, _ := e.String()
+	switch strings.ToLower(v) {
+	case "true", "1", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
+
+// writeExtraFields appends the front matter entries no generator owns, keeping their
+// original order, formatting and comments.
+func writeExtraFields(sb *strings.Builder, metadata model.RulesMetadata) {
+	for _, e := range metadata.ExtraFields {
+		if isReservedField(e.Key) {
+			continue
+		}
+		sb.WriteString(e.YAML())
+	}
 }
 
 func quoteIfNeeded(s string) string {
@@ -0,0 +1,161 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/util"
+)
+
+const (
+	RecordSync = "sync"
+	RecordUndo = "undo"
+
+	idLayout = "20060102T150405.000000Z"
+)
+
+// Change describes a single file written as part of a batch.
+// PrevHash is empty when the file did not exist before the batch.
+type Change struct {
+	Path     string `json:"path"`
+	Agent    string `json:"agent,omitempty"`
+	PrevHash string `json:"prev_hash,omitempty"`
+	NewHash  string `json:"new_hash,omitempty"`
+}
+
+// Record is one line of the journal: a batch of writes made together.
+type Record struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Source  string    `json:"source,omitempty"`
+	Undoes  string    `json:"undoes,omitempty"`
+	Changes []Change  `json:"changes"`
+}
+
+// Journal is an append-only log of write batches (JSON lines) plus a content-addressed
+// store holding copies of the content each batch overwrote.
+type Journal struct {
+	path    string
+	objects string
+}
+
+func New(path, objectsDir string) *Journal {
+	return &Journal{path: path, objects: objectsDir}
+}
+
+// Store saves data in the object store and returns its hash.
+func (j *Journal) Store(data []byte) (string, error) {
+	hash := util.Hash(data)
+	path := filepath.Join(j.objects, hash)
+	if util.IsFileExists(path) {
+		return hash, nil
+	}
+	if err := util.WriteFile(path, data); err != nil {
+		return "", fmt.Errorf("store object: %w", err)
+	}
+	return hash, nil
+}
+
+// Object returns the content stored under hash.
+func (j *Journal) Object(hash string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(j.objects, hash))
+	if err != nil {
+		return nil, fmt.Errorf("read object %s: %w", hash, err)
+	}
+	return data, nil
+}
+
+// Append assigns an ID and time to the record and appends it to the journal.
+func (j *Journal) Append(r Record) (Record, error) {
+	now := time.Now().UTC()
+	r.ID = now.Format(idLayout)
+	r.Time = now
+	line, err := json.Marshal(r)
+	if err != nil {
+		return r, fmt.Errorf("encode journal record: %w", err)
+	}
+	if err := util.EnsureDir(filepath.Dir(j.path)); err != nil {
+		return r, err
+	}
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return r, fmt.Errorf("open journal: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return r, fmt.Errorf("append journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return r, fmt.Errorf("fsync journal: %w", err)
+	}
+	return r, nil
+}
+
+// Records returns all journal records, oldest first.
+func (j *Journal) Records() ([]Record, error) {
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("parse journal line %d: %w", n, err)
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	return records, nil
+}
+
+// Undoable returns the sync record with the given ID, or the latest sync record
+// that has not been undone yet when id is empty.
+func (j *Journal) Undoable(id string) (Record, error) {
+	records, err := j.Records()
+	if err != nil {
+		return Record{}, err
+	}
+	undone := make(map[string]bool)
+	for _, r := range records {
+		if r.Type == RecordUndo {
+			undone[r.Undoes] = true
+		}
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if r.Type != RecordSync {
+			continue
+		}
+		if id == "" && !undone[r.ID] {
+			return r, nil
+		}
+		if r.ID == id {
+			if undone[r.ID] {
+				return Record{}, fmt.Errorf("sync %s has already been undone", id)
+			}
+			return r, nil
+		}
+	}
+	if id == "" {
+		return Record{}, fmt.Errorf("nothing to undo")
+	}
+	return Record{}, fmt.Errorf("sync %s not found in journal", id)
+}
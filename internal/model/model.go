@@ -1,7 +1,12 @@
 package model
 
 import (
+	"fmt"
+	"strings"
 	"time"
+
+	yaml "github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
 )
 
 type Kind string
@@ -16,29 +21,164 @@ const (
 const (
 	AgentCursor  string = "cursor"
 	AgentCopilot string = "copilot"
+	AgentCline   string = "cline"
+	AgentKiro    string = "kiro"
 )
 
+// GlobAll is the normalized glob matching every file.
+const GlobAll = "**"
+
 type FileInfo struct {
 	Path    string
 	ModTime time.Time
 }
 
+// MetadataEntry is one top-level key of a document's YAML front matter.
+type MetadataEntry struct {
+	Key string
+	// Value is the decoded value: a scalar, a []any or a map[string]any.
+	Value any
+	// Node is the parsed entry including its comments and original formatting.
+	// It is nil for entries that were not parsed from YAML.
+	Node ast.Node
+}
+
+// String returns the entry as a trimmed scalar string. It reports false for
+// empty values, lists and maps.
+func (e MetadataEntry) String() (string, bool) {
+	switch v := e.Value.(type) {
+	case nil:
+		return "", false
+	case string:
+		sv := strings.TrimSpace(v)
+		if sv == "" || strings.EqualFold(sv, "<nil>") {
+			return "", false
+		}
+		return sv, true
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// Strings returns a scalar as a single-element list, or the scalar items of a list.
+func (e MetadataEntry) Strings() []string {
+	if s, ok := e.String(); ok {
+		return []string{s}
+	}
+	items, ok := e.Value.([]any)
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := (MetadataEntry{Value: item}).String(); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// YAML renders the entry as front matter lines, reusing the original text when available.
+func (e MetadataEntry) YAML() string {
+	if e.Node != nil {
+		return strings.TrimRight(e.Node.String(), "\n") + "\n"
+	}
+	out, err := yaml.MarshalWithOptions(yaml.MapSlice{{Key: e.Key, Value: e.Value}}, yaml.IndentSequence(true))
+	if err != nil {
+		return fmt.Sprintf("%s: %v\n", e.Key, e.Value)
+	}
+	return string(out)
+}
+
+// DocumentMetadata is a document's YAML front matter as ordered top-level entries,
+// so lists, nested maps, comments and key order survive a round trip.
 type DocumentMetadata struct {
-	Raw map[string]string
+	Entries []MetadataEntry
+}
+
+// Get returns the entry with the given key, compared case-insensitively.
+func (m DocumentMetadata) Get(key string) (MetadataEntry, bool) {
+	for _, e := range m.Entries {
+		if strings.EqualFold(e.Key, key) {
+			return e, true
+		}
+	}
+	return MetadataEntry{}, false
+}
+
+// Values returns the decoded values of all entries by key.
+func (m DocumentMetadata) Values() map[string]any {
+	if len(m.Entries) == 0 {
+		return nil
+	}
+	values := make(map[string]any, len(m.Entries))
+	for _, e := range m.Entries {
+		values[e.Key] = e.Value
+	}
+	return values
 }
 
 type Document struct {
+	// Agent is the name of the agent the document belongs to.
+	Agent    string
 	FileInfo FileInfo
 	Metadata DocumentMetadata
 	Content  []byte
 }
 
+// Activation is how an agent decides to apply a rule.
+type Activation string
+
+const (
+	// ActivationUnknown means the format did not state the activation; see RulesMetadata.Mode.
+	ActivationUnknown Activation = ""
+	// ActivationAlways applies the rule to every request.
+	ActivationAlways Activation = "always"
+	// ActivationGlobs attaches the rule automatically when matching files are involved.
+	ActivationGlobs Activation = "globs"
+	// ActivationRequested lets the agent decide to use the rule based on its description.
+	ActivationRequested Activation = "requested"
+	// ActivationManual applies the rule only when the user references it explicitly.
+	ActivationManual Activation = "manual"
+)
+
+// MergePolicy selects how the rule metadata of all copies in a stack is combined.
+type MergePolicy string
+
+const (
+	// MergeChanged takes the changed copy as authoritative for every field its format can
+	// express, so clearing a field deletes it. Older copies only fill in fields the changed
+	// format cannot tell apart.
+	MergeChanged MergePolicy = "changed"
+	// MergeNewestValue takes, for every field, the newest non-empty value of any copy.
+	MergeNewestValue MergePolicy = "newest-value"
+)
+
 type RulesMetadata struct {
+	Activation  Activation
 	Description string
-	Globs       string
-	ExtraFields map[string]string
+	// Globs is the normalized list of file patterns the rule applies to.
+	Globs []string
+	// ExtraFields are the front matter entries no generator owns, in document order.
+	ExtraFields []MetadataEntry
 }
 
-func (m *RulesMetadata) IsAlwaysApply() bool {
-	return m.Globs == "**" || m.Globs == "*"
+// Mode returns the effective activation. When no format stated it, a rule with globs is
+// auto-attached and a rule without globs is always applied.
+func (m *RulesMetadata) Mode() Activation {
+	switch m.Activation {
+	case ActivationRequested, ActivationManual, ActivationAlways:
+		return m.Activation
+	}
+	if len(m.Globs) == 0 {
+		return ActivationAlways
+	}
+	for _, g := range m.Globs {
+		if g == GlobAll {
+			return ActivationAlways
+		}
+	}
+	return ActivationGlobs
 }
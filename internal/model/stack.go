@@ -5,6 +5,19 @@ type Properties struct {
 	Stem string
 }
 
+// Key identifies the logical item shared by all agents' copies.
+func (p Properties) Key() string {
+	return string(p.Kind) + "|" + p.Stem
+}
+
+// String returns the name of the item used on the command line, e.g. "rules:go" or "context".
+func (p Properties) String() string {
+	if p.Stem == "" {
+		return string(p.Kind)
+	}
+	return string(p.Kind) + ":" + p.Stem
+}
+
 type DocumentStack struct {
 	Documents   []Document
 	Properties  Properties
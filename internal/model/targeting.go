@@ -0,0 +1,83 @@
+package model
+
+import "strings"
+
+// TargetingKey is the front matter key that limits which agents a rule is synced to, either
+// as a map or as dotted keys:
+//
+//	syncai:
+//	  agents: [cursor, windsurf]
+//	  skip: [copilot]
+//
+//	syncai.skip: [copilot]
+//
+// It belongs to SyncAI and is never copied to other agents.
+const TargetingKey = "syncai"
+
+// IsTargetingKey reports whether a front matter key is the targeting entry or one of its
+// dotted forms.
+func IsTargetingKey(key string) bool {
+	key = strings.ToLower(key)
+	return key == TargetingKey || strings.HasPrefix(key, TargetingKey+".")
+}
+
+// Targeting lists the agents a rule is meant for. An empty Agents list means all agents;
+// Skip removes agents from that set.
+type Targeting struct {
+	Agents []string
+	Skip   []string
+}
+
+// ParseTargeting reads the targeting entries of the front matter. It reports false when the
+// document has none.
+func ParseTargeting(m DocumentMetadata) (Targeting, bool) {
+	var t Targeting
+	found := false
+	for _, e := range m.Entries {
+		key := strings.ToLower(e.Key)
+		if key == TargetingKey {
+			values, ok := e.Value.(map[string]any)
+			if !ok {
+				continue
+			}
+			for k, v := range values {
+				found = t.set(k, v) || found
+			}
+			continue
+		}
+		if field, ok := strings.CutPrefix(key, TargetingKey+"."); ok {
+			found = t.set(field, e.Value) || found
+		}
+	}
+	return t, found
+}
+
+func (t *Targeting) set(field string, value any) bool {
+	switch strings.ToLower(field) {
+	case "agents":
+		t.Agents = MetadataEntry{Value: value}.Strings()
+	case "skip":
+		t.Skip = MetadataEntry{Value: value}.Strings()
+	default:
+		return false
+	}
+	return true
+}
+
+// Allows reports whether the rule is synced to the agent.
+func (t Targeting) Allows(agent string) bool {
+	for _, s := range t.Skip {
+		if strings.EqualFold(s, agent) {
+			return false
+		}
+	}
+	if len(t.Agents) == 0 {
+		return true
+	}
+	for _, a := range t.Agents {
+		if strings.EqualFold(a, agent) {
+			return true
+		}
+	}
+	return false
+}
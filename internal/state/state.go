@@ -0,0 +1,73 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/util"
+)
+
+// Tombstone records that a logical item was deleted on purpose, so stale copies
+// found later are removed instead of being propagated again.
+type Tombstone struct {
+	DeletedAt time.Time `json:"deleted_at"`
+	Path      string    `json:"path"`
+}
+
+// State is SyncAI's persistent bookkeeping, stored as JSON in the state directory.
+type State struct {
+	path       string
+	Tombstones map[string]Tombstone `json:"tombstones"`
+}
+
+// Load reads the state file at path. A missing file yields an empty state.
+func Load(path string) (*State, error) {
+	st := &State{path: path, Tombstones: make(map[string]Tombstone)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return st, fmt.Errorf("read state: %w", err)
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return &State{path: path, Tombstones: make(map[string]Tombstone)}, fmt.Errorf("parse state %s: %w", path, err)
+	}
+	if st.Tombstones == nil {
+		st.Tombstones = make(map[string]Tombstone)
+	}
+	return st, nil
+}
+
+// Save writes the state file atomically.
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+	if err := util.WriteFile(s.path, append(data, '\n')); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	return nil
+}
+
+func (s *State) Tombstone(key string) (Tombstone, bool) {
+	t, ok := s.Tombstones[key]
+	return t, ok
+}
+
+// Bury records a tombstone for key, replacing any previous one.
+func (s *State) Bury(key, path string, at time.Time) {
+	s.Tombstones[key] = Tombstone{DeletedAt: at.UTC(), Path: path}
+}
+
+// Unbury removes the tombstone for key and reports whether one existed.
+func (s *State) Unbury(key string) bool {
+	if _, ok := s.Tombstones[key]; !ok {
+		return false
+	}
+	delete(s.Tombstones, key)
+	return true
+}
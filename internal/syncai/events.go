@@ -0,0 +1,140 @@
+package syncai
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Event is a sync event as it is logged, e.g. a synced or trashed file or a failed sync.
+// Type is the value of the "event" field; Attrs holds the fields that have no dedicated member.
+type Event struct {
+	Time    time.Time
+	Level   slog.Level
+	Type    string
+	Message string
+	Agent   string
+	Kind    string
+	Stem    string
+	Src     string
+	Dst     string
+	Err     error
+	Attrs   map[string]any
+}
+
+// Subscribe registers fn for every event, including debug events the logger filters out.
+// fn runs synchronously on the syncing goroutine, which may hold the lock of s, so it must
+// not block or call other methods of s. The returned function removes the subscription.
+func (s *SyncAI) Subscribe(fn func(Event)) func() {
+	return s.events.subscribe(fn)
+}
+
+type eventBus struct {
+	mu   sync.RWMutex
+	next int
+	subs map[int]func(Event)
+}
+
+func (b *eventBus) subscribe(fn func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[int]func(Event))
+	}
+	id := b.next
+	b.next++
+	b.subs[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+func (b *eventBus) active() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs) > 0
+}
+
+func (b *eventBus) publish(e Event) {
+	b.mu.RLock()
+	subs := make([]func(Event), 0, len(b.subs))
+	for _, fn := range b.subs {
+		subs = append(subs, fn)
+	}
+	b.mu.RUnlock()
+	for _, fn := range subs {
+		fn(e)
+	}
+}
+
+// eventHandler passes log records on to the configured handler and publishes those that
+// carry an "event" field to the subscribers, so every logged event is also observable.
+type eventHandler struct {
+	next  slog.Handler
+	bus   *eventBus
+	attrs []slog.Attr
+}
+
+func (h *eventHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.bus.active() || h.next.Enabled(ctx, level)
+}
+
+func (h *eventHandler) Handle(ctx context.Context, r slog.Record) error {
+	var err error
+	if h.next.Enabled(ctx, r.Level) {
+		err = h.next.Handle(ctx, r)
+	}
+	if !h.bus.active() {
+		return err
+	}
+	e := Event{Time: r.Time, Level: r.Level, Message: r.Message}
+	for _, a := range h.attrs {
+		e.set(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		e.set(a)
+		return true
+	})
+	if e.Type != "" {
+		h.bus.publish(e)
+	}
+	return err
+}
+
+func (h *eventHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &eventHandler{next: h.next.WithAttrs(attrs), bus: h.bus, attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)}
+}
+
+func (h *eventHandler) WithGroup(name string) slog.Handler {
+	return &eventHandler{next: h.next.WithGroup(name), bus: h.bus, attrs: h.attrs}
+}
+
+func (e *Event) set(a slog.Attr) {
+	v := a.Value.Resolve()
+	switch a.Key {
+	case "event":
+		e.Type = v.String()
+	case "agent":
+		e.Agent = v.String()
+	case "kind":
+		e.Kind = v.String()
+	case "stem":
+		e.Stem = v.String()
+	case "src":
+		e.Src = v.String()
+	case "dst":
+		e.Dst = v.String()
+	case "error":
+		if err, ok := v.Any().(error); ok {
+			e.Err = err
+		}
+	default:
+		if e.Attrs == nil {
+			e.Attrs = make(map[string]any)
+		}
+		e.Attrs[a.Key] = v.Any()
+	}
+}
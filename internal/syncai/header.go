@@ -0,0 +1,91 @@
+package syncai
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/flowmitry/syncai/internal/model"
+	"github.com/flowmitry/syncai/internal/util"
+)
+
+// provenance is the header of a copy written for an agent with `generator.header` enabled:
+//
+//	<!-- Generated by SyncAI from .cursor/rules/go.mdc (sha256:0123456789ab). -->
+//
+// It is the first line of the body, after any front matter, and `#` comments are used for
+// ignore files. Hash is that of the copy without the header, so a copy whose hash no longer
+// matches was edited by hand.
+type provenance struct {
+	Source string
+	Hash   string
+}
+
+var provenancePattern = regexp.MustCompile(`^(?:<!-- |# )Generated by SyncAI from (.+) \(sha256:([0-9a-f]{12})\)\.(?: -->)?\r?\n`)
+
+// cutProvenance removes the header from the start of a body.
+func cutProvenance(body []byte) (provenance, []byte, bool) {
+	m := provenancePattern.FindSubmatch(body)
+	if m == nil {
+		return provenance{}, body, false
+	}
+	return provenance{Source: string(m[1]), Hash: string(m[2])}, body[len(m[0]):], true
+}
+
+// splitProvenance finds the header of a file and returns the file without it.
+func splitProvenance(data []byte, kind model.Kind) (provenance, []byte, bool) {
+	offset := bodyOffset(data, kind)
+	p, body, ok := cutProvenance(data[offset:])
+	if !ok {
+		return p, data, false
+	}
+	return p, append(append([]byte{}, data[:offset]...), body...), true
+}
+
+// withProvenance inserts the header naming source into generated content.
+func withProvenance(data []byte, kind model.Kind, source string) []byte {
+	var header string
+	if kind == model.KindIgnore {
+		header = fmt.Sprintf("# Generated by SyncAI from %s (sha256:%s).\n", source, shortHash(data))
+	} else {
+		header = fmt.Sprintf("<!-- Generated by SyncAI from %s (sha256:%s). -->\n", source, shortHash(data))
+	}
+	offset := bodyOffset(data, kind)
+	out := make([]byte, 0, len(data)+len(header))
+	out = append(out, data[:offset]...)
+	out = append(out, header...)
+	return append(out, data[offset:]...)
+}
+
+// bodyOffset returns where the body starts, after the front matter of rules and context files.
+func bodyOffset(data []byte, kind model.Kind) int {
+	if kind == model.KindIgnore {
+		return 0
+	}
+	_, body := util.Parse(data)
+	return len(data) - len(body)
+}
+
+func shortHash(data []byte) string {
+	return util.Hash(data)[:12]
+}
+
+// handEdited reports whether the file carries a header that no longer matches its content.
+func handEdited(data []byte, kind model.Kind) (provenance, bool) {
+	p, rest, ok := splitProvenance(data, kind)
+	return p, ok && shortHash(rest) != p.Hash
+}
+
+// origin returns the file a sync from path is generated from: the source recorded in the
+// header of an unedited generated copy, so syncing from that copy keeps the headers of the
+// others as they are, or else path itself.
+func (s *SyncAI) origin(path string, kind model.Kind) string {
+	data, err := os.ReadFile(s.abs(path))
+	if err != nil {
+		return path
+	}
+	if p, rest, ok := splitProvenance(data, kind); ok && shortHash(rest) == p.Hash {
+		return p.Source
+	}
+	return path
+}
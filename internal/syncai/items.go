@@ -0,0 +1,200 @@
+package syncai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/model"
+)
+
+// Copy is one agent's file for a logical item.
+type Copy struct {
+	Agent   string
+	Path    string
+	Exists  bool
+	ModTime time.Time
+}
+
+// Item is a logical file (kind+stem) together with every agent's copy of it.
+type Item struct {
+	Properties model.Properties
+	Copies     []Copy
+}
+
+// Newest returns the most recently modified existing copy.
+func (i Item) Newest() (Copy, bool) {
+	var newest Copy
+	found := false
+	for _, c := range i.Copies {
+		if c.Exists && (!found || c.ModTime.After(newest.ModTime)) {
+			newest = c
+			found = true
+		}
+	}
+	return newest, found
+}
+
+// Preview is the outcome of syncing to one destination, computed without writing anything.
+type Preview struct {
+	Agent    string
+	Path     string
+	Exists   bool
+	Current  []byte
+	Proposed []byte
+}
+
+// Changed reports whether syncing would modify the destination.
+func (p Preview) Changed() bool {
+	return !p.Exists || !bytes.Equal(p.Current, p.Proposed)
+}
+
+// Items returns every logical item that has at least one existing copy, sorted by key.
+func (s *SyncAI) Items() []Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.items()
+}
+
+func (s *SyncAI) items() []Item {
+	s.resetScopes()
+	props := make(map[string]model.Properties)
+	for _, agent := range s.cfg.Agents {
+		for _, path := range s.files(&agent) {
+			if _, kind, stem := s.Identify(path); kind != model.KindUnknown {
+				p := model.Properties{Kind: kind, Stem: stem}
+				props[p.Key()] = p
+			}
+		}
+	}
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	items := make([]Item, 0, len(keys))
+	for _, k := range keys {
+		p := props[k]
+		item := Item{Properties: p}
+		for i := range s.cfg.Agents {
+			agent := &s.cfg.Agents[i]
+			path := s.generatePath(agent, p.Kind, p.Stem)
+			if path == "" {
+				continue
+			}
+			c := Copy{Agent: agent.Name, Path: path}
+			if fi, err := os.Stat(s.abs(path)); err == nil && !fi.IsDir() {
+				c.Exists = true
+				c.ModTime = fi.ModTime()
+			}
+			item.Copies = append(item.Copies, c)
+		}
+		if _, ok := item.Newest(); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// SyncAll picks the newest version among agents for each logical item and propagates it.
+// It stops between items when ctx is cancelled and returns ctx.Err().
+func (s *SyncAI) SyncAll(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.syncAll(ctx)
+}
+
+func (s *SyncAI) syncAll(ctx context.Context) error {
+	return s.syncItems(ctx, nil)
+}
+
+// syncItems syncs the items accepted by filter, or all items when filter is nil.
+func (s *SyncAI) syncItems(ctx context.Context, filter func(Item) bool) error {
+	s.log.Info("Initial sync started", "event", "initial_sync_start")
+	for _, item := range s.items() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if filter != nil && !filter(item) {
+			continue
+		}
+		newest, ok := item.Newest()
+		if !ok {
+			continue
+		}
+		if _, err := s.syncFile(ctx, newest.Path); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.log.Error("Initial sync failed", itemAttrs("sync_error", newest.Agent, item.Properties, newest.Path, "error", err)...)
+		}
+	}
+	s.log.Info("Initial sync completed", "event", "initial_sync_done")
+	return nil
+}
+
+// DryRun reports every write SyncAll would make as a "would_sync" event, without changing anything.
+func (s *SyncAI) DryRun(ctx context.Context) ([]Preview, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]Preview, 0)
+	for _, item := range s.items() {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		newest, ok := item.Newest()
+		if !ok {
+			continue
+		}
+		previews, _, err := s.preview(ctx, newest.Path)
+		if err != nil {
+			s.log.Error("Dry run failed", itemAttrs("sync_error", newest.Agent, item.Properties, newest.Path, "error", err)...)
+			continue
+		}
+		for _, p := range previews {
+			if p.Changed() {
+				s.log.Info("File would be synced", itemAttrs("would_sync", p.Agent, item.Properties, newest.Path, "dst", p.Path)...)
+				result = append(result, p)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Preview computes what Sync(path) would write to every other agent, and to path itself
+// when it is not in the canonical form of its agent.
+func (s *SyncAI) Preview(path string) ([]Preview, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previews, _, err := s.preview(context.Background(), path)
+	return previews, err
+}
+
+// preview is Preview that also returns the plan, for the agents that get no copy.
+func (s *SyncAI) preview(ctx context.Context, path string) ([]Preview, syncPlan, error) {
+	path = s.rel(path)
+	srcAgent, kind, stem := s.Identify(path)
+	if kind == model.KindUnknown || srcAgent == nil {
+		return nil, syncPlan{}, fmt.Errorf("%s is not a watched file", path)
+	}
+	plan, err := s.plan(ctx, path, srcAgent, model.Properties{Kind: kind, Stem: stem})
+	if err != nil {
+		return nil, syncPlan{}, err
+	}
+	previews := make([]Preview, 0, len(plan.writes))
+	for _, w := range plan.writes {
+		p := Preview{Agent: w.agent, Path: w.path, Proposed: w.data}
+		if data, err := os.ReadFile(s.abs(w.path)); err == nil {
+			p.Exists = true
+			p.Current = data
+		} else if !os.IsNotExist(err) {
+			return nil, syncPlan{}, fmt.Errorf("read %s: %w", w.path, err)
+		}
+		previews = append(previews, p)
+	}
+	return previews, plan, nil
+}
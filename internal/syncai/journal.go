@@ -0,0 +1,131 @@
+package syncai
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flowmitry/syncai/internal/journal"
+	"github.com/flowmitry/syncai/internal/util"
+)
+
+// Journal returns the journal of sync batches, or nil when it is disabled.
+func (s *SyncAI) Journal() *journal.Journal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.journal
+}
+
+// recordBatch appends the writes that will change files to the journal, keeping a copy
+// of every overwritten file. It is called before the writes are applied.
+func (s *SyncAI) recordBatch(source string, writes []pendingWrite) (journal.Record, error) {
+	if s.journal == nil {
+		return journal.Record{}, nil
+	}
+	changes := make([]journal.Change, 0, len(writes))
+	for _, w := range writes {
+		change := journal.Change{Path: w.path, Agent: w.agent, NewHash: util.Hash(w.data)}
+		prev, err := os.ReadFile(s.abs(w.path))
+		if err == nil {
+			if bytes.Equal(prev, w.data) {
+				continue
+			}
+			if err := util.EnsureStateDir(s.abs(s.cfg.StateDir())); err != nil {
+				return journal.Record{}, err
+			}
+			if change.PrevHash, err = s.journal.Store(prev); err != nil {
+				return journal.Record{}, err
+			}
+		} else if !os.IsNotExist(err) {
+			return journal.Record{}, fmt.Errorf("read %s: %w", w.path, err)
+		}
+		changes = append(changes, change)
+	}
+	if len(changes) == 0 {
+		return journal.Record{}, nil
+	}
+	if err := util.EnsureStateDir(s.abs(s.cfg.StateDir())); err != nil {
+		return journal.Record{}, err
+	}
+	return s.journal.Append(journal.Record{Type: journal.RecordSync, Source: source, Changes: changes})
+}
+
+// Undo rolls back the sync batch with the given ID, or the latest one when id is empty.
+// Every file of the batch must still hold the content the batch wrote, unless force is set;
+// otherwise nothing is changed. Files created by the batch are removed (into the trash if enabled).
+// If a file cannot be restored or removed, the files already restored get back the content
+// they had before the undo, and the batch can be undone again.
+func (s *SyncAI) Undo(id string, force bool) (journal.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.journal == nil {
+		return journal.Record{}, fmt.Errorf("journal is disabled")
+	}
+	rec, err := s.journal.Undoable(id)
+	if err != nil {
+		return journal.Record{}, err
+	}
+
+	// Verify and load everything up front, keeping the current contents for a rollback.
+	restores := make([]appliedWrite, 0, len(rec.Changes))
+	toRemove := make([]string, 0)
+	for _, c := range rec.Changes {
+		cur, err := os.ReadFile(s.abs(c.Path))
+		existed := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return rec, fmt.Errorf("read %s: %w", c.Path, err)
+		}
+		curHash := ""
+		if existed {
+			curHash = util.Hash(cur)
+		}
+		if curHash != c.NewHash && !force {
+			return rec, fmt.Errorf("%s changed since sync %s; use -force to undo anyway", c.Path, rec.ID)
+		}
+		if c.PrevHash == "" {
+			if existed {
+				toRemove = append(toRemove, c.Path)
+			}
+			continue
+		}
+		data, err := s.journal.Object(c.PrevHash)
+		if err != nil {
+			return rec, err
+		}
+		restores = append(restores, appliedWrite{pendingWrite: pendingWrite{agent: c.Agent, path: c.Path, data: data}, prev: cur, existed: existed})
+	}
+
+	for i, w := range restores {
+		if err := util.WriteFile(s.abs(w.path), w.data); err != nil {
+			return rec, s.abortUndo(restores[:i], fmt.Errorf("restore %s: %w", w.path, err))
+		}
+	}
+	if removed, err := s.discard(toRemove); err != nil {
+		err = fmt.Errorf("remove files created by sync %s: %w", rec.ID, err)
+		if len(removed) > 0 {
+			err = fmt.Errorf("%w (already removed: %s)", err, strings.Join(removed, ", "))
+		}
+		return rec, s.abortUndo(restores, err)
+	}
+
+	reverted := make([]journal.Change, 0, len(rec.Changes))
+	for _, c := range rec.Changes {
+		reverted = append(reverted, journal.Change{Path: c.Path, Agent: c.Agent, PrevHash: c.NewHash, NewHash: c.PrevHash})
+	}
+	_, err = s.journal.Append(journal.Record{Type: journal.RecordUndo, Source: rec.Source, Undoes: rec.ID, Changes: reverted})
+	return rec, err
+}
+
+// abortUndo puts back the files an undo already restored. It returns cause, joined with
+// any error of putting them back.
+func (s *SyncAI) abortUndo(restored []appliedWrite, cause error) error {
+	errs := []error{cause}
+	for i := len(restored) - 1; i >= 0; i-- {
+		if err := s.unapply(restored[i]); err != nil {
+			errs = append(errs, fmt.Errorf("roll back %s: %w", restored[i].path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,42 @@
+package syncai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flowmitry/syncai/internal/config"
+)
+
+func TestUndo(t *testing.T) {
+	root := t.TempDir()
+	s := newTestSyncAI(t, root,
+		config.Agent{Name: "cursor", Rules: config.Rules{Pattern: ".cursor/rules/*.mdc"}},
+		config.Agent{Name: "cline", Rules: config.Rules{Pattern: ".clinerules/*.md"}},
+	)
+	writeFile(t, root, ".clinerules/go.md", "Use go vet.\n")
+	writeFile(t, root, ".cursor/rules/go.mdc", "---\ndescription: \"\"\nalwaysApply: true\nglobs: \n---\nUse gofmt.\n")
+	if _, err := s.Sync(context.Background(), ".cursor/rules/go.mdc"); err != nil {
+		t.Fatal(err)
+	}
+	cline := filepath.Join(root, ".clinerules/go.md")
+
+	writeFile(t, root, ".clinerules/go.md", "Edited.\n")
+	if _, err := s.Undo("", false); err == nil {
+		t.Fatal("undo of a changed file succeeded without force")
+	}
+	if data, _ := os.ReadFile(cline); string(data) != "Edited.\n" {
+		t.Errorf("refused undo changed the file to %q", data)
+	}
+
+	if _, err := s.Undo("", true); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(cline); string(data) != "Use go vet.\n" {
+		t.Errorf("undo restored %q, want the content before the sync", data)
+	}
+	if _, err := s.Undo("", false); err == nil {
+		t.Error("the same sync was undone twice")
+	}
+}
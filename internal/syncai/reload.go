@@ -0,0 +1,99 @@
+package syncai
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/flowmitry/syncai/internal/config"
+	"github.com/flowmitry/syncai/internal/model"
+)
+
+// Reload switches to a new configuration. Agents that were added, and the sections of
+// agents that changed, get an initial sync; all other items are left as they are.
+// While Watch is running, the switch waits for the current scan to finish. An invalid
+// configuration, or one with a different working directory, is rejected and the current
+// one is kept; a configuration without a working directory keeps the current root.
+func (s *SyncAI) Reload(ctx context.Context, cfg config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if dir := cfg.WorkingDir(); dir != "" {
+		if abs, err := filepath.Abs(dir); err != nil || abs != s.root {
+			return fmt.Errorf("working directory cannot change from %s to %s while running", s.root, dir)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := s.changedKinds(s.cfg, cfg.Rooted(s.root))
+	s.configure(cfg)
+	s.reloaded = true
+	s.log.Info("Configuration reloaded", "event", "config_reloaded", "agents", len(cfg.Agents), "changed_agents", len(changed))
+	if len(changed) == 0 {
+		return nil
+	}
+	return s.syncItems(ctx, func(item Item) bool {
+		for _, c := range item.Copies {
+			if changed[c.Agent][item.Properties.Kind] {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// changedKinds compares the agents of two configurations by name and returns, for every
+// agent of next, the kinds whose section was added or changed. A change of the agent's or
+// the global exclude patterns counts as a change of all its sections.
+func (s *SyncAI) changedKinds(prev, next config.Config) map[string]map[model.Kind]bool {
+	old := make(map[string]config.Agent, len(prev.Agents))
+	for _, a := range prev.Agents {
+		old[strings.ToLower(a.Name)] = a
+	}
+	changed := make(map[string]map[model.Kind]bool)
+	for _, a := range next.Agents {
+		key := strings.ToLower(a.Name)
+		o, existed := old[key]
+		delete(old, key)
+
+		rescoped := !existed || !slices.Equal(o.Exclude, a.Exclude) || !slices.Equal(prev.Meta.Exclude, next.Meta.Exclude)
+		// Turning the header on or off rewrites every copy of the agent
+		rescoped = rescoped || o.Generator.Header != a.Generator.Header
+		kinds := make(map[model.Kind]bool)
+		if len(a.Rules.All()) > 0 && (rescoped || !reflect.DeepEqual(o.Rules, a.Rules) || !reflect.DeepEqual(o.Generator, a.Generator)) {
+			kinds[model.KindRules] = true
+		}
+		if strings.TrimSpace(a.Context.Path) != "" && (rescoped || o.Context != a.Context) {
+			kinds[model.KindContext] = true
+		}
+		if strings.TrimSpace(a.Ignore.Path) != "" && (rescoped || o.Ignore != a.Ignore) {
+			kinds[model.KindIgnore] = true
+		}
+		switch {
+		case !existed:
+			s.log.Info("Agent added", "event", "agent_added", "agent", a.Name)
+		case len(kinds) > 0:
+			s.log.Info("Agent changed", "event", "agent_changed", "agent", a.Name, "kinds", kindNames(kinds))
+		}
+		if len(kinds) > 0 {
+			changed[a.Name] = kinds
+		}
+	}
+	for _, a := range old {
+		s.log.Info("Agent removed, its files are no longer synced", "event", "agent_removed", "agent", a.Name)
+	}
+	return changed
+}
+
+func kindNames(kinds map[model.Kind]bool) string {
+	names := make([]string, 0, len(kinds))
+	for _, k := range []model.Kind{model.KindRules, model.KindContext, model.KindIgnore} {
+		if kinds[k] {
+			names = append(names, string(k))
+		}
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,154 @@
+package syncai
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/config"
+	"github.com/flowmitry/syncai/internal/generator"
+)
+
+// Agents that do not read nested context files get them as scoped rules instead:
+// services/api/AGENTS.md is synced to the rule `services-api` with the glob `services/api/**`,
+// and such a rule is identified as the nested context item again. Directories whose names
+// only differ in `/` and `-`, such as a/b-c and a-b/c, would share a rule; they are reported
+// and get no scoped rule at all.
+
+func scopedRuleStem(dir string) string {
+	return strings.ReplaceAll(dir, "/", "-")
+}
+
+// takesScopedRules reports whether nested context items are written to the agent as rules.
+func takesScopedRules(a *config.Agent) bool {
+	if len(a.Rules.Parsed()) == 0 {
+		return false
+	}
+	p, ok := a.Context.Parsed()
+	return !ok || !p.Nested
+}
+
+// scopeClashes reports whether the scoped rule of the directory is shared with another one.
+func (s *SyncAI) scopeClashes(dir string) bool {
+	other, ok := s.scopeDirs()[scopedRuleStem(dir)]
+	return ok && other == ""
+}
+
+func (s *SyncAI) hasNestedContext() bool {
+	for _, a := range s.cfg.Agents {
+		if p, ok := a.Context.Parsed(); ok && p.Nested {
+			return true
+		}
+	}
+	return false
+}
+
+// scopedRule is the outcome of checking a rule file, kept until the file changes.
+type scopedRule struct {
+	modTime time.Time
+	size    int64
+	dir     string
+	ok      bool
+}
+
+// resetScopes forgets the nested context directories, so they are listed again on the next
+// lookup. It is called before every scan.
+func (s *SyncAI) resetScopes() {
+	s.scopeMu.Lock()
+	defer s.scopeMu.Unlock()
+	s.scopes = nil
+}
+
+// scopeDirs returns the directories that have a nested context file, by scoped rule stem.
+// A stem shared by several directories maps to "". The map is not changed once returned.
+func (s *SyncAI) scopeDirs() map[string]string {
+	s.scopeMu.Lock()
+	defer s.scopeMu.Unlock()
+	if s.scopes != nil {
+		return s.scopes
+	}
+	s.scopes = make(map[string]string)
+	for i := range s.cfg.Agents {
+		a := &s.cfg.Agents[i]
+		p, ok := a.Context.Parsed()
+		if !ok || !p.Nested {
+			continue
+		}
+		files, _ := p.Glob(s.root)
+		for _, f := range files {
+			dir, ok := p.Match(f)
+			if !ok || dir == "" || s.excluded(a, f) {
+				continue
+			}
+			stem := scopedRuleStem(dir)
+			if other, seen := s.scopes[stem]; seen && other != dir {
+				s.scopes[stem] = ""
+				if !s.clashes[stem] {
+					s.clashes[stem] = true
+					s.log.Error("Nested context directories share a scoped rule name, not writing it", "event", "scope_conflict", "stem", stem, "dirs", []string{other, dir})
+				}
+				continue
+			}
+			s.scopes[stem] = dir
+		}
+	}
+	return s.scopes
+}
+
+// scopedContext returns the directory of the nested context item that the agent's rule file
+// with the given stem stands for. A rule qualifies when its only glob is `<dir>/**` and its
+// stem is the directory with `-` separators. A deleted rule qualifies when a nested context
+// file of that directory still exists.
+//
+// Identify calls it for every rule file, so the outcome is kept per file until it changes.
+func (s *SyncAI) scopedContext(a *config.Agent, path, stem string) (string, bool) {
+	if !takesScopedRules(a) || !s.hasNestedContext() {
+		return "", false
+	}
+	fi, err := os.Stat(s.abs(path))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", false
+		}
+		dir := s.scopeDirs()[stem]
+		return dir, dir != ""
+	}
+	if dir, ok := s.scopeDirs()[stem]; ok && dir == "" {
+		return "", false
+	}
+	s.scopeMu.Lock()
+	r, ok := s.scopedRules[path]
+	s.scopeMu.Unlock()
+	if ok && r.modTime.Equal(fi.ModTime()) && r.size == fi.Size() {
+		return r.dir, r.ok
+	}
+	dir, ok := s.ruleScope(a, path, stem)
+	s.scopeMu.Lock()
+	s.scopedRules[path] = scopedRule{modTime: fi.ModTime(), size: fi.Size(), dir: dir, ok: ok}
+	s.scopeMu.Unlock()
+	return dir, ok
+}
+
+// ruleScope checks the glob of a rule file. Identify must not run commands, so the rules of
+// an agent with an external generator are matched by name against the existing nested
+// context directories instead.
+func (s *SyncAI) ruleScope(a *config.Agent, path, stem string) (string, bool) {
+	gen := s.gens.Get(a.Name)
+	if _, external := gen.(generator.ExternalRulesGenerator); external {
+		dir := s.scopeDirs()[stem]
+		return dir, dir != ""
+	}
+	doc, err := s.parseFile(path)
+	if err != nil {
+		return "", false
+	}
+	rules, err := gen.ParseRules(doc.Metadata)
+	if err != nil || len(rules.Globs) != 1 {
+		return "", false
+	}
+	dir, ok := strings.CutSuffix(rules.Globs[0], "/**")
+	if !ok || dir == "" || scopedRuleStem(dir) != stem {
+		return "", false
+	}
+	return dir, true
+}
@@ -0,0 +1,45 @@
+package syncai
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/flowmitry/syncai/internal/config"
+)
+
+func TestScopedCachesConcurrentUse(t *testing.T) {
+	root := t.TempDir()
+	s := newTestSyncAI(t, root,
+		config.Agent{Name: "claude", Context: config.Context{Path: "**/CLAUDE.md"}},
+		config.Agent{Name: "cursor", Rules: config.Rules{Pattern: ".cursor/rules/*.mdc"}},
+	)
+	writeFile(t, root, "services/api/CLAUDE.md", "Run the API tests.\n")
+	if err := s.SyncAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run with -race: scans reset the caches while Status and Items fill them
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				s.resetScopes()
+				s.Status()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				s.Items()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if a := agentStatus(t, s.Status(), "context:services/api", "cursor"); !a.InSync {
+		t.Errorf("scoped rule is %q, want it in sync", a.State)
+	}
+}
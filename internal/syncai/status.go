@@ -0,0 +1,165 @@
+package syncai
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/util"
+)
+
+const (
+	StateLatest    = "latest"
+	StateInSync    = "in sync"
+	StateOutOfSync = "out of sync"
+	StateMissing   = "missing"
+	// StateSkipped is an agent the item is not targeted at, which has no copy of it.
+	StateSkipped = "skipped"
+	// StateUntargeted is a copy left in an agent the item is not targeted at; a sync removes it.
+	StateUntargeted = "untargeted"
+	// StateUnsupported is an agent without a rules pattern that can hold a nested rule.
+	StateUnsupported = "unsupported"
+)
+
+// MetadataDiff is a front matter key whose value differs from what a sync would write.
+type MetadataDiff struct {
+	Key      string `json:"key"`
+	Current  any    `json:"current,omitempty"`
+	Expected any    `json:"expected,omitempty"`
+}
+
+// AgentStatus describes one agent's copy of an item. HandEdited is set for a generated copy
+// that was changed after SyncAI wrote it.
+type AgentStatus struct {
+	Agent        string         `json:"agent"`
+	Path         string         `json:"path"`
+	Exists       bool           `json:"exists"`
+	ModTime      *time.Time     `json:"mod_time,omitempty"`
+	BodyHash     string         `json:"body_hash,omitempty"`
+	Metadata     map[string]any `json:"metadata,omitempty"`
+	MetadataDiff []MetadataDiff `json:"metadata_diff,omitempty"`
+	Latest       bool           `json:"latest"`
+	InSync       bool           `json:"in_sync"`
+	State        string         `json:"state"`
+	HandEdited   bool           `json:"hand_edited,omitempty"`
+}
+
+// ItemStatus describes a logical item and all agents' copies of it.
+type ItemStatus struct {
+	Item   string        `json:"item"`
+	Kind   string        `json:"kind"`
+	Stem   string        `json:"stem"`
+	Latest string        `json:"latest"`
+	InSync bool          `json:"in_sync"`
+	Error  string        `json:"error,omitempty"`
+	Agents []AgentStatus `json:"agents"`
+}
+
+// Report is the sync state of every item.
+type Report struct {
+	InSync bool         `json:"in_sync"`
+	Items  []ItemStatus `json:"items"`
+}
+
+// Status compares every agent's copy of every item with what syncing the latest copy would write.
+func (s *SyncAI) Status() Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report := Report{InSync: true, Items: make([]ItemStatus, 0)}
+	for _, item := range s.items() {
+		newest, _ := item.Newest()
+		st := ItemStatus{
+			Item:   item.Properties.String(),
+			Kind:   string(item.Properties.Kind),
+			Stem:   item.Properties.Stem,
+			Latest: newest.Path,
+			InSync: true,
+		}
+
+		previews, plan, err := s.preview(context.Background(), newest.Path)
+		if err != nil {
+			st.Error = err.Error()
+			st.InSync = false
+		}
+		expected := make(map[string]Preview)
+		for _, p := range previews {
+			expected[p.Path] = p
+		}
+		skipped := make(map[string]bool)
+		for _, path := range plan.untargeted {
+			skipped[path] = true
+		}
+
+		for _, c := range item.Copies {
+			as := AgentStatus{Agent: c.Agent, Path: c.Path, Exists: c.Exists, Latest: c.Path == newest.Path}
+			if c.Exists {
+				modTime := c.ModTime
+				as.ModTime = &modTime
+				if data, err := os.ReadFile(s.abs(c.Path)); err == nil {
+					metadata, body := util.Parse(data)
+					_, body, _ = cutProvenance(body)
+					as.BodyHash = util.Hash(body)
+					_, as.HandEdited = handEdited(data, item.Properties.Kind)
+					as.Metadata = metadata.Values()
+				}
+			}
+			p, planned := expected[c.Path]
+			switch {
+			case as.Latest:
+				as.InSync = true
+				as.State = StateLatest
+			case skipped[c.Path] && c.Exists:
+				as.State = StateUntargeted
+			case skipped[c.Path]:
+				as.InSync = true
+				as.State = StateSkipped
+			case !c.Exists:
+				as.State = StateMissing
+			case planned && p.Changed():
+				as.State = StateOutOfSync
+				as.MetadataDiff = diffMetadata(as.Metadata, p.Proposed)
+			default:
+				as.InSync = true
+				as.State = StateInSync
+			}
+			if !as.InSync {
+				st.InSync = false
+			}
+			st.Agents = append(st.Agents, as)
+		}
+		// An agent that cannot hold the nested rule has no copy; it counts as drift
+		for _, agent := range plan.unsupported {
+			st.Agents = append(st.Agents, AgentStatus{Agent: agent, State: StateUnsupported})
+			st.InSync = false
+		}
+		if !st.InSync {
+			report.InSync = false
+		}
+		report.Items = append(report.Items, st)
+	}
+	return report
+}
+
+func diffMetadata(current map[string]any, proposed []byte) []MetadataDiff {
+	expectedMeta, _ := util.Parse(proposed)
+	expected := expectedMeta.Values()
+	keys := make(map[string]bool)
+	for k := range current {
+		keys[k] = true
+	}
+	for k := range expected {
+		keys[k] = true
+	}
+	diffs := make([]MetadataDiff, 0)
+	for k := range keys {
+		if !reflect.DeepEqual(current[k], expected[k]) {
+			diffs = append(diffs, MetadataDiff{Key: k, Current: current[k], Expected: expected[k]})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Key < diffs[j].Key
+	})
+	return diffs
+}
@@ -0,0 +1,140 @@
+package syncai
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/config"
+	"github.com/flowmitry/syncai/internal/util"
+)
+
+func writeFile(t *testing.T, root, path, content string) {
+	t.Helper()
+	abs := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestSyncAI(t *testing.T, root string, agents ...config.Agent) *SyncAI {
+	t.Helper()
+	cfg := config.Config{Meta: config.Meta{WorkingDir: root}, Agents: agents}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return New(cfg, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+}
+
+func agentStatus(t *testing.T, report Report, item, agent string) AgentStatus {
+	t.Helper()
+	for _, it := range report.Items {
+		if it.Item != item {
+			continue
+		}
+		for _, a := range it.Agents {
+			if a.Agent == agent {
+				return a
+			}
+		}
+	}
+	t.Fatalf("no status for %s of %s", agent, item)
+	return AgentStatus{}
+}
+
+func TestStatusSkippedAgent(t *testing.T) {
+	root := t.TempDir()
+	s := newTestSyncAI(t, root,
+		config.Agent{Name: "cursor", Rules: config.Rules{Pattern: ".cursor/rules/*.mdc"}},
+		config.Agent{Name: "copilot", Rules: config.Rules{Pattern: ".github/instructions/*.instructions.md"}},
+		config.Agent{Name: "cline", Rules: config.Rules{Pattern: ".clinerules/*.md"}},
+	)
+	writeFile(t, root, ".cursor/rules/go.mdc", "---\ndescription: \"\"\nalwaysApply: true\nglobs: \nsyncai:\n  skip: [copilot]\n---\nUse gofmt.\n")
+	if err := s.SyncAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	report := s.Status()
+	if !report.InSync {
+		t.Errorf("report is out of sync after a sync: %+v", report)
+	}
+	if a := agentStatus(t, report, "rules:go", "copilot"); a.State != StateSkipped || !a.InSync {
+		t.Errorf("copilot is %q (in sync %v), want %q", a.State, a.InSync, StateSkipped)
+	}
+
+	// A copy left in the skipped agent is drift until a sync removes it
+	writeFile(t, root, ".github/instructions/go.instructions.md", "Use gofmt.\n")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(root, ".github/instructions/go.instructions.md"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	report = s.Status()
+	if a := agentStatus(t, report, "rules:go", "copilot"); a.State != StateUntargeted || a.InSync {
+		t.Errorf("stale copilot copy is %q (in sync %v), want %q", a.State, a.InSync, StateUntargeted)
+	}
+	if report.InSync {
+		t.Error("report is in sync with a stale copy in a skipped agent")
+	}
+}
+
+func TestStatusNestedRuleUnsupported(t *testing.T) {
+	root := t.TempDir()
+	s := newTestSyncAI(t, root,
+		config.Agent{Name: "cursor", Rules: config.Rules{Pattern: ".cursor/rules/**/*.mdc"}},
+		config.Agent{Name: "copilot", Rules: config.Rules{Pattern: ".github/instructions/*.instructions.md"}},
+		config.Agent{Name: "cline", Rules: config.Rules{Pattern: ".clinerules/*.md", Flatten: true}},
+	)
+	writeFile(t, root, ".cursor/rules/backend/api.mdc", "---\ndescription: \"\"\nalwaysApply: true\nglobs: \n---\nVersion the API.\n")
+	if err := s.SyncAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	report := s.Status()
+	if a := agentStatus(t, report, "rules:backend/api", "copilot"); a.State != StateUnsupported || a.InSync {
+		t.Errorf("copilot is %q (in sync %v), want %q", a.State, a.InSync, StateUnsupported)
+	}
+	if a := agentStatus(t, report, "rules:backend/api", "cline"); !a.InSync {
+		t.Errorf("flattening cline is %q, want it in sync", a.State)
+	}
+	if report.InSync {
+		t.Error("report is in sync although copilot cannot hold the nested rule")
+	}
+}
+
+func TestStatusInSyncAfterFirstSync(t *testing.T) {
+	root := t.TempDir()
+	s := newTestSyncAI(t, root,
+		config.Agent{Name: "cursor", Rules: config.Rules{Pattern: ".cursor/rules/*.mdc"}},
+		config.Agent{Name: "copilot", Rules: config.Rules{Pattern: ".github/instructions/*.instructions.md"}},
+		config.Agent{Name: "kiro", Rules: config.Rules{Pattern: ".kiro/steering/*.md"}},
+	)
+	writeFile(t, root, ".cursor/rules/go.mdc", "---\nglobs: **/*.go\nalwaysApply: false\nowner: platform\n---\nUse gofmt.\n")
+	if err := s.SyncAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if report := s.Status(); !report.InSync {
+		t.Errorf("report is out of sync after the first sync: %+v", report)
+	}
+	previews, err := s.DryRun(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range previews {
+		t.Errorf("a second sync would write %s:\n%s", p.Path, p.Proposed)
+	}
+	data, err := os.ReadFile(filepath.Join(root, ".cursor/rules/go.mdc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata, body := util.Parse(data); string(body) != "Use gofmt.\n" || metadata.Values()["owner"] != "platform" {
+		t.Errorf("source lost its body or extra fields:\n%s", data)
+	}
+}
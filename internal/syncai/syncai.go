@@ -1,44 +1,192 @@
 package syncai
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"github.com/flowmitry/syncai/internal/generator"
+	"github.com/flowmitry/syncai/internal/journal"
+	"github.com/flowmitry/syncai/internal/model"
+	"github.com/flowmitry/syncai/internal/state"
+	"github.com/flowmitry/syncai/internal/trash"
+	"github.com/flowmitry/syncai/internal/util"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
-	"syncai/internal/generator"
-	"syncai/internal/model"
-	"syncai/internal/util"
+	"sync"
+	"time"
 
-	"syncai/internal/config"
+	"github.com/flowmitry/syncai/internal/config"
 )
 
 type SyncAI struct {
-	cfg config.Config
+	cfg      config.Config
+	root     string
+	log      *slog.Logger
+	trash    *trash.Trash
+	state    *state.State
+	journal  *journal.Journal
+	gens     generator.Generators
+	custom   generator.Generators
+	excludes map[string]*util.PathMatcher
+	events   *eventBus
+
+	// scopes and scopedRules let Identify tell scoped rules apart without reading every
+	// rule file again; see scopedContext. clashes holds the reported shared stems.
+	// Status and Items fill them too, so they have their own lock, scopeMu.
+	scopes      map[string]string
+	scopedRules map[string]scopedRule
+	clashes     map[string]bool
+	scopeMu     sync.Mutex
+
+	// mu is held by every exported method that reads the configuration, and by each watch
+	// scan for its whole duration, so a new configuration never takes effect in the middle
+	// of a batch and the instance can be used while Watch runs.
+	mu sync.Mutex
+	// reloaded tells Watch to take a fresh snapshot of the files before its next scan.
+	reloaded bool
 }
 
-func New(cfg config.Config) *SyncAI {
-	return &SyncAI{cfg: cfg}
+// Option customizes a SyncAI instance created by New.
+type Option func(*SyncAI)
+
+// WithLogger sets the logger used for sync events. The default is slog.Default().
+func WithLogger(l *slog.Logger) Option {
+	return func(s *SyncAI) {
+		s.log = l
+	}
+}
+
+// WithGenerator uses gen for the rules of the named agent, overriding both the built-in
+// generator and an external generator from the configuration.
+func WithGenerator(agent string, gen generator.RulesGenerator) Option {
+	return func(s *SyncAI) {
+		s.custom[strings.ToLower(agent)] = gen
+	}
+}
+
+func New(cfg config.Config, opts ...Option) *SyncAI {
+	s := &SyncAI{log: slog.Default(), custom: make(generator.Generators), events: &eventBus{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.log = slog.New(&eventHandler{next: s.log.Handler(), bus: s.events})
+	s.root = cfg.WorkingDir()
+	if abs, err := filepath.Abs(s.root); err == nil {
+		s.root = abs
+	}
+	s.configure(cfg)
+	return s
+}
+
+// configure sets up everything derived from the configuration.
+func (s *SyncAI) configure(cfg config.Config) {
+	s.cfg = cfg.Rooted(s.root)
+	s.excludes = make(map[string]*util.PathMatcher)
+	for _, a := range s.cfg.Agents {
+		// Patterns were checked by Validate; an invalid one excludes nothing
+		if m, err := s.cfg.Exclude(a); err == nil {
+			s.excludes[strings.ToLower(a.Name)] = m
+		}
+	}
+	s.scopeMu.Lock()
+	s.scopes = nil
+	s.scopedRules = make(map[string]scopedRule)
+	s.clashes = make(map[string]bool)
+	s.scopeMu.Unlock()
+	s.gens = make(generator.Generators)
+	for _, a := range cfg.Agents {
+		if a.Generator.IsExternal() {
+			s.gens[strings.ToLower(a.Name)] = generator.ExternalRulesGenerator{
+				Agent:   a.Name,
+				Command: a.Generator.Command,
+				Timeout: a.Generator.TimeoutDuration(),
+				Dir:     s.root,
+			}
+		}
+	}
+	for name, gen := range s.custom {
+		s.gens[name] = gen
+	}
+	s.trash = nil
+	if !cfg.Meta.Trash.Disabled {
+		s.trash = trash.New(s.root, cfg.TrashDir(), cfg.TrashRetention())
+	}
+	s.journal = nil
+	if !cfg.Meta.Journal.Disabled {
+		s.journal = journal.New(s.abs(cfg.JournalPath()), s.abs(cfg.ObjectsDir()))
+	}
+	st, err := state.Load(s.abs(cfg.StatePath()))
+	if err != nil {
+		s.log.Warn("State could not be loaded, starting with an empty state", "event", "state_error", "error", err)
+	}
+	s.state = st
+}
+
+// Root returns the absolute working directory. Paths are kept relative to it, as in the
+// configuration, and resolved against it for every file access.
+func (s *SyncAI) Root() string {
+	return s.root
+}
+
+// Trash returns the trash used for deleted copies, or nil when it is disabled.
+func (s *SyncAI) Trash() *trash.Trash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.trash
 }
 
 // Delete propagates deletion of a watched file to corresponding destinations across other agents.
-func (s *SyncAI) Delete(path string) ([]string, error) {
+// Unless the trash is disabled, the copies are moved into the trash rather than removed.
+// A propagated deletion leaves a tombstone so stale copies are not re-created later.
+func (s *SyncAI) Delete(ctx context.Context, path string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteFile(ctx, path)
+}
+
+func (s *SyncAI) deleteFile(ctx context.Context, path string) ([]string, error) {
 	result := make([]string, 0)
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	path = s.rel(path)
 	srcAgent, kind, stem := s.Identify(path)
 	result = append(result, path)
 	if kind == model.KindUnknown || srcAgent == nil {
 		return result, nil // nothing to do
 	}
 
-	// Only propagate deletions for rules. Context/ignore deletions are not propagated to avoid accidental removals.
-	if kind != model.KindRules {
+	// Context/ignore deletions are only propagated when enabled, to avoid accidental removals.
+	if !s.propagatesDelete(kind) {
 		return result, nil
 	}
 
+	removed, err := s.removeCopies(kind, stem, srcAgent.Name)
+	result = append(result, removed...)
+	if err != nil {
+		return result, err
+	}
+
+	props := model.Properties{Kind: kind, Stem: stem}
+	s.state.Bury(props.Key(), path, time.Now())
+	if err := s.saveState(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// removeCopies removes every agent's copy of the item except the one of skipAgent.
+// The returned paths include destinations that were already missing.
+func (s *SyncAI) removeCopies(kind model.Kind, stem string, skipAgent string) ([]string, error) {
+	result := make([]string, 0)
+	toRemove := make([]string, 0)
 	for i := range s.cfg.Agents {
 		dstAgent := &s.cfg.Agents[i]
-		if srcAgent.Name == dstAgent.Name {
+		if dstAgent.Name == skipAgent {
 			continue
 		}
 
@@ -46,35 +194,224 @@ func (s *SyncAI) Delete(path string) ([]string, error) {
 		if dstPath == "" {
 			continue
 		}
-		if err := os.Remove(dstPath); err != nil {
-			if os.IsNotExist(err) {
-				// Already gone at the destination; nothing to do
-				result = append(result, dstPath)
-				continue
-			}
-			return result, err
-		} else {
+		if !util.IsFileExists(s.abs(dstPath)) {
+			// Already gone at the destination; nothing to do
 			result = append(result, dstPath)
+			continue
 		}
+		toRemove = append(toRemove, dstPath)
 	}
-	return result, nil
+	if len(toRemove) == 0 {
+		return result, nil
+	}
+
+	removed, err := s.discard(toRemove)
+	result = append(result, removed...)
+	return result, err
+}
+
+// discard moves the files into a single trash entry, or removes them when the trash is disabled.
+func (s *SyncAI) discard(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	if s.trash == nil {
+		removed := make([]string, 0, len(paths))
+		for _, path := range paths {
+			if err := os.Remove(s.abs(path)); err != nil && !os.IsNotExist(err) {
+				return removed, err
+			}
+			removed = append(removed, path)
+		}
+		return removed, nil
+	}
+
+	if err := util.EnsureStateDir(s.abs(s.cfg.StateDir())); err != nil {
+		return nil, err
+	}
+	entry, err := s.trash.Move(paths)
+	if err != nil {
+		return entry.Files, err
+	}
+	for _, path := range entry.Files {
+		s.log.Info("File moved to trash", "event", "trash", "dst", path, "trash_id", entry.ID)
+	}
+	return entry.Files, nil
+}
+
+func (s *SyncAI) saveState() error {
+	if err := util.EnsureStateDir(s.abs(s.cfg.StateDir())); err != nil {
+		return err
+	}
+	return s.state.Save()
 }
 
 // Sync propagates creation/update of a watched file across other agents.
-func (s *SyncAI) Sync(path string) ([]string, error) {
+// Cancelling ctx aborts the sync until the first file is written; after that the batch is
+// finished, and a failed write rolls back the files already written.
+func (s *SyncAI) Sync(ctx context.Context, path string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.syncFile(ctx, path)
+}
+
+func (s *SyncAI) syncFile(ctx context.Context, path string) ([]string, error) {
 	result := make([]string, 0)
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	path = s.rel(path)
 	srcAgent, kind, stem := s.Identify(path)
 	if kind == model.KindUnknown || srcAgent == nil {
 		return result, nil // unknown file, ignore
 	}
 
+	props := model.Properties{Kind: kind, Stem: stem}
+	if tomb, ok := s.state.Tombstone(props.Key()); ok {
+		fi, err := os.Stat(s.abs(path))
+		if err != nil {
+			return result, fmt.Errorf("stat %s: %w", path, err)
+		}
+		if fi.ModTime().Before(tomb.DeletedAt) {
+			// A copy older than the deletion is stale; finish the deletion instead of resurrecting it.
+			s.log.Info("File predates its deletion, removing stale copies", itemAttrs("stale", srcAgent.Name, props, path, "deleted_at", tomb.DeletedAt)...)
+			_, err := s.removeCopies(kind, stem, "")
+			return result, err
+		}
+		s.state.Unbury(props.Key())
+		if err := s.saveState(); err != nil {
+			return result, err
+		}
+	}
+
+	if data, err := os.ReadFile(s.abs(path)); err == nil {
+		if p, edited := handEdited(data, kind); edited {
+			s.log.Warn("Generated file was edited by hand", itemAttrs("hand_edit", srcAgent.Name, props, path, "generated_from", p.Source)...)
+		}
+	}
+
+	plan, err := s.plan(ctx, path, srcAgent, props)
+	if err != nil {
+		return result, err
+	}
+	writes := plan.writes
+	for _, agent := range plan.unsupported {
+		s.log.Warn("Nested rule not synced, no rules pattern of the agent is recursive or flattening", itemAttrs("nested_rule_unsupported", agent, props, path)...)
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	rec, err := s.recordBatch(path, writes)
+	if err != nil {
+		return result, fmt.Errorf("journal: %w", err)
+	}
+	applied := make([]appliedWrite, 0, len(writes))
+	for _, w := range writes {
+		cur, err := os.ReadFile(s.abs(w.path))
+		existed := err == nil
+		if existed && bytes.Equal(cur, w.data) {
+			result = append(result, w.path)
+			s.log.Debug("File already in sync", itemAttrs("unchanged", w.agent, props, path, "dst", w.path)...)
+			continue
+		}
+		if err := util.WriteFile(s.abs(w.path), w.data); err != nil {
+			err = fmt.Errorf("write %s for agent %s: %w", w.path, w.agent, err)
+			return result, s.rollback(rec, applied, props, path, err)
+		}
+		applied = append(applied, appliedWrite{pendingWrite: w, prev: cur, existed: existed})
+		result = append(result, w.path)
+		s.log.Info("File synced", itemAttrs("sync", w.agent, props, path, "dst", w.path)...)
+	}
+
+	// Copies left in agents the rule is no longer targeted at are removed like deleted ones.
+	stale := make([]string, 0, len(plan.untargeted))
+	for _, dst := range plan.untargeted {
+		if util.IsFileExists(s.abs(dst)) {
+			stale = append(stale, dst)
+		}
+	}
+	removed, err := s.discard(stale)
+	for _, dst := range removed {
+		s.log.Info("File removed from untargeted agent", itemAttrs("untargeted", srcAgent.Name, props, path, "dst", dst)...)
+	}
+	result = append(result, removed...)
+	if err != nil {
+		return result, fmt.Errorf("remove untargeted copies: %w", err)
+	}
+
+	return result, nil
+}
+
+// appliedWrite is a write of the current batch together with what it replaced.
+type appliedWrite struct {
+	pendingWrite
+	prev    []byte
+	existed bool
+}
+
+// rollback reverts the writes of a batch that failed part way and marks its journal record
+// as undone. It returns cause, joined with any error of the rollback itself.
+func (s *SyncAI) rollback(rec journal.Record, applied []appliedWrite, props model.Properties, src string, cause error) error {
+	errs := []error{cause}
+	reverted := make([]journal.Change, 0, len(applied))
+	for i := len(applied) - 1; i >= 0; i-- {
+		w := applied[i]
+		if err := s.unapply(w); err != nil {
+			errs = append(errs, fmt.Errorf("roll back %s: %w", w.path, err))
+			continue
+		}
+		s.log.Warn("File rolled back", itemAttrs("rollback", w.agent, props, src, "dst", w.path)...)
+		change := journal.Change{Path: w.path, Agent: w.agent, PrevHash: util.Hash(w.data)}
+		if w.existed {
+			change.NewHash = util.Hash(w.prev)
+		}
+		reverted = append(reverted, change)
+	}
+	if s.journal != nil && rec.ID != "" {
+		if _, err := s.journal.Append(journal.Record{Type: journal.RecordUndo, Source: src, Undoes: rec.ID, Changes: reverted}); err != nil {
+			errs = append(errs, fmt.Errorf("journal: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unapply puts back what a write replaced, removing a file it created.
+func (s *SyncAI) unapply(w appliedWrite) error {
+	if w.existed {
+		return util.WriteFile(s.abs(w.path), w.prev)
+	}
+	if err := os.Remove(s.abs(w.path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+type pendingWrite struct {
+	agent string
+	path  string
+	data  []byte
+}
+
+// syncPlan is what syncing an item does to the other agents.
+type syncPlan struct {
+	writes []pendingWrite
+	// untargeted are the destinations of agents the rule is not targeted at.
+	untargeted []string
+	// unsupported are the agents whose rules patterns cannot hold the rule's nested stem.
+	unsupported []string
+}
+
+// plan builds the document stack for the item and generates the content of every other agent's copy,
+// and of the source itself when rewritesSource allows it.
+// Every destination is generated before anything is written, so a failing generator leaves no agent half-synced.
+// It also lists the destinations of agents a rule is not targeted at, whose existing copies Sync
+// removes, and the agents that cannot hold a nested rule.
+func (s *SyncAI) plan(ctx context.Context, path string, srcAgent *config.Agent, props model.Properties) (syncPlan, error) {
 	stack := model.DocumentStack{
 		Documents:   make([]model.Document, 0),
 		ChangedPath: path,
-		Properties: model.Properties{
-			Kind: kind,
-			Stem: stem,
-		},
+		Properties:  props,
 	}
 	for i := range s.cfg.Agents {
 		dstAgent := &s.cfg.Agents[i]
@@ -83,117 +420,152 @@ func (s *SyncAI) Sync(path string) ([]string, error) {
 		if dstAgent.Name == srcAgent.Name {
 			docPath = path
 		} else {
-			docPath = s.generatePath(dstAgent, kind, stem)
+			docPath = s.generatePath(dstAgent, props.Kind, props.Stem)
 			if docPath == "" {
 				continue
 			}
 		}
-		if util.IsFileExists(docPath) {
-			doc, err := util.ParseFile(docPath)
+		if util.IsFileExists(s.abs(docPath)) {
+			doc, err := s.parseFile(docPath)
 			if err != nil {
-				return result, fmt.Errorf("parse %s for agent %s: %w", docPath, dstAgent.Name, err)
+				return syncPlan{}, fmt.Errorf("parse %s for agent %s: %w", docPath, dstAgent.Name, err)
 			}
+			doc.Agent = dstAgent.Name
 			stack.Push(doc)
 		}
 	}
 
+	// The targeting only applies to destinations: the rule always stays with the agent it
+	// was changed in and with the one whose file declares the targeting.
+	target, holder, targeted := ruleTargeting(&stack)
+
+	source := s.origin(path, props.Kind)
+	gens := s.gens.Session(ctx)
+	plan := syncPlan{writes: make([]pendingWrite, 0, len(s.cfg.Agents)), untargeted: make([]string, 0)}
 	for i := range s.cfg.Agents {
 		dstAgent := &s.cfg.Agents[i]
 		if srcAgent.Name == dstAgent.Name {
+			if !s.rewritesSource(path, props.Kind) {
+				continue
+			}
+			// The source gets the canonical form of its agent too, so one sync settles every file
+			data, err := s.generate(&stack, dstAgent, gens)
+			if err != nil {
+				return syncPlan{}, fmt.Errorf("generate stack for agent %s: %w", dstAgent.Name, err)
+			}
+			plan.writes = append(plan.writes, pendingWrite{agent: dstAgent.Name, path: path, data: data})
 			continue
 		}
 
-		dstPath := s.generatePath(dstAgent, kind, stem)
+		skipped := targeted && dstAgent.Name != holder && !target.Allows(dstAgent.Name)
+		dstPath := s.generatePath(dstAgent, props.Kind, props.Stem)
 		if strings.TrimSpace(dstPath) == "" {
 			// No target path configured for this agent/kind; skip writing
+			if !skipped && holdsNoNested(dstAgent, props.Kind, props.Stem) {
+				plan.unsupported = append(plan.unsupported, dstAgent.Name)
+			}
+			continue
+		}
+		if skipped {
+			plan.untargeted = append(plan.untargeted, dstPath)
 			continue
 		}
-		data, err := generate(&stack, dstAgent.Name)
+		if err := ctx.Err(); err != nil {
+			return syncPlan{}, err
+		}
+		data, err := s.generate(&stack, dstAgent, gens)
 		if err != nil {
-			return result, fmt.Errorf("generate stack for agent %s: %w", dstAgent.Name, err)
+			return syncPlan{}, fmt.Errorf("generate stack for agent %s: %w", dstAgent.Name, err)
 		}
-		if err = util.WriteFile(dstPath, data); err != nil {
-			return result, fmt.Errorf("write %s for agent %s: %w", dstPath, dstAgent.Name, err)
+		if dstAgent.Generator.Header {
+			data = withProvenance(data, props.Kind, source)
 		}
-		result = append(result, dstPath)
-		log.Printf("File %s synced to %s", path, dstPath)
+		plan.writes = append(plan.writes, pendingWrite{agent: dstAgent.Name, path: dstPath, data: data})
 	}
+	return plan, nil
+}
 
-	return result, nil
+// rewritesSource reports whether a sync from path writes path itself in canonical form. Only
+// rules written by hand are; a file with a provenance header is left as it is.
+func (s *SyncAI) rewritesSource(path string, kind model.Kind) bool {
+	if kind != model.KindRules {
+		return false
+	}
+	data, err := os.ReadFile(s.abs(path))
+	if err != nil {
+		return false
+	}
+	_, _, generated := splitProvenance(data, kind)
+	return !generated
 }
 
+// Identify returns the agent, kind and stem of a file. The path may be relative to the root,
+// with or without a leading `./`, or absolute.
 func (s *SyncAI) Identify(path string) (*config.Agent, model.Kind, string) {
-	clean := filepath.Clean(path)
+	clean := s.rel(path)
 	for i := range s.cfg.Agents {
 		a := &s.cfg.Agents[i]
-		if filepath.Clean(a.Context.Path) == clean {
-			return a, model.KindContext, ""
+		if s.excluded(a, clean) {
+			continue
+		}
+		if p, ok := a.Context.Parsed(); ok {
+			if stem, ok := p.Match(clean); ok {
+				return a, model.KindContext, stem
+			}
 		}
-		if filepath.Clean(a.Ignore.Path) == clean {
+		if a.Ignore.Path != "" && a.Ignore.Path == clean {
 			return a, model.KindIgnore, ""
 		}
-		if strings.TrimSpace(a.Rules.Pattern) != "" {
-			// Match by comparing the directory and base pattern, independent of file existence
-			pattern := a.Rules.Pattern
-			patDir := filepath.Clean(filepath.Dir(pattern))
-			fileDir := filepath.Clean(filepath.Dir(clean))
-			if patDir == fileDir {
-				basePattern := filepath.Base(pattern)
-				filename := filepath.Base(clean)
-				// Attempt to extract stem depending on wildcard presence
-				if strings.Contains(basePattern, "*") {
-					parts := strings.Split(basePattern, "*")
-					prefix := parts[0]
-					suffix := ""
-					if len(parts) > 1 {
-						suffix = parts[len(parts)-1]
-					}
-					if strings.HasPrefix(filename, prefix) && strings.HasSuffix(filename, suffix) {
-						stem := strings.TrimPrefix(filename, prefix)
-						stem = strings.TrimSuffix(stem, suffix)
-						return a, model.KindRules, stem
-					}
-				} else {
-					if filename == basePattern {
-						stem := filename
-						if ext := filepath.Ext(stem); ext != "" {
-							stem = strings.TrimSuffix(stem, ext)
-						}
-						return a, model.KindRules, stem
-					}
+		for _, pattern := range a.Rules.Parsed() {
+			// Match by the pattern's directory and file name, independent of file existence
+			if stem, ok := pattern.Match(clean); ok {
+				if dir, ok := s.scopedContext(a, clean, stem); ok {
+					return a, model.KindContext, dir
 				}
+				return a, model.KindRules, stem
 			}
 		}
 	}
 	return nil, model.KindUnknown, ""
 }
 
-func generate(s *model.DocumentStack, agent string) ([]byte, error) {
+func (s *SyncAI) generate(stack *model.DocumentStack, agent *config.Agent, gens generator.Generators) ([]byte, error) {
 	// Sort documents by ModTime.
 	// The document with ChangedPath is always considered the "newest" and placed last,
 	// regardless of its actual modification time. This ensures that the changed document
 	// is prioritized for further processing, even if its ModTime is older than others.
-	sort.Slice(s.Documents, func(i, j int) bool {
-		if s.Documents[i].FileInfo.Path == s.ChangedPath {
+	sort.Slice(stack.Documents, func(i, j int) bool {
+		if stack.Documents[i].FileInfo.Path == stack.ChangedPath {
 			return false
 		}
-		if s.Documents[j].FileInfo.Path == s.ChangedPath {
+		if stack.Documents[j].FileInfo.Path == stack.ChangedPath {
 			return true
 		}
-		return s.Documents[i].FileInfo.ModTime.Before(s.Documents[j].FileInfo.ModTime)
+		return stack.Documents[i].FileInfo.ModTime.Before(stack.Documents[j].FileInfo.ModTime)
 	})
 
-	if len(s.Documents) == 0 {
+	if len(stack.Documents) == 0 {
 		return []byte{}, fmt.Errorf("no documents in stack")
 	}
-	newestDoc := s.Documents[len(s.Documents)-1]
+	newestDoc := stack.Documents[len(stack.Documents)-1]
 	content := newestDoc.Content
-	agentName := strings.ToLower(agent)
 
-	if s.Properties.Kind == model.KindRules {
-		if gen := generator.GetRulesGenerator(agentName); gen != nil {
-			metadata := generator.ExtractRulesMetadata(s)
-			content = gen.GenerateRules(metadata, content)
+	// Nested context written as a scoped rule applies to its directory
+	scoped := stack.Properties.Kind == model.KindContext && stack.Properties.Stem != "" && takesScopedRules(agent)
+	if stack.Properties.Kind == model.KindRules || scoped {
+		if gen := gens.Get(agent.Name); gen != nil {
+			metadata, err := generator.ExtractRulesMetadata(stack, s.cfg.MergePolicy(), gens)
+			if err != nil {
+				return nil, err
+			}
+			if scoped {
+				metadata.Activation = model.ActivationGlobs
+				metadata.Globs = []string{stack.Properties.Stem + "/**"}
+			}
+			keepOwnTargeting(&metadata, stack, agent)
+			if content, err = gen.GenerateRules(metadata, content); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -0,0 +1,57 @@
+package syncai
+
+import (
+	"github.com/flowmitry/syncai/internal/config"
+	"github.com/flowmitry/syncai/internal/model"
+)
+
+// ruleTargeting returns the agents the rule in the stack is meant for: the targeting of the
+// changed copy, or else that of the newest copy which has one. Copies written by SyncAI
+// never carry the key, so it is found in the file where it was written by hand; the agent of
+// that file is returned as well, since the rule always stays with it.
+func ruleTargeting(stack *model.DocumentStack) (model.Targeting, string, bool) {
+	if stack.Properties.Kind != model.KindRules {
+		return model.Targeting{}, "", false
+	}
+	var found *model.Document
+	var target model.Targeting
+	for i := range stack.Documents {
+		d := &stack.Documents[i]
+		t, ok := model.ParseTargeting(d.Metadata)
+		if !ok {
+			continue
+		}
+		if d.FileInfo.Path == stack.ChangedPath {
+			return t, d.Agent, true
+		}
+		if found == nil || d.FileInfo.ModTime.After(found.FileInfo.ModTime) {
+			found, target = d, t
+		}
+	}
+	if found == nil {
+		return model.Targeting{}, "", false
+	}
+	return target, found.Agent, true
+}
+
+// keepOwnTargeting removes the targeting entries from the metadata generated for the agent,
+// unless the agent's own copy is the one holding them.
+func keepOwnTargeting(metadata *model.RulesMetadata, stack *model.DocumentStack, agent *config.Agent) {
+	extra := make([]model.MetadataEntry, 0, len(metadata.ExtraFields))
+	for _, e := range metadata.ExtraFields {
+		if !model.IsTargetingKey(e.Key) {
+			extra = append(extra, e)
+		}
+	}
+	for _, d := range stack.Documents {
+		if d.Agent != agent.Name {
+			continue
+		}
+		for _, e := range d.Metadata.Entries {
+			if model.IsTargetingKey(e.Key) {
+				extra = append(extra, e)
+			}
+		}
+	}
+	metadata.ExtraFields = extra
+}
@@ -1,42 +1,137 @@
 package syncai
 
 import (
-	"path/filepath"
+	"github.com/flowmitry/syncai/internal/config"
+	"github.com/flowmitry/syncai/internal/model"
+	"github.com/flowmitry/syncai/internal/util"
 	"strings"
-	"syncai/internal/config"
-	"syncai/internal/model"
 )
 
+// itemAttrs returns the common log fields of an event concerning an item, followed by extra key-value pairs.
+func itemAttrs(event, agent string, props model.Properties, src string, extra ...any) []any {
+	attrs := []any{"event", event, "agent", agent, "kind", string(props.Kind), "stem", props.Stem, "src", src}
+	return append(attrs, extra...)
+}
+
+// propagatesDelete reports whether deleting an item of the given kind removes the other agents' copies.
+func (s *SyncAI) propagatesDelete(kind model.Kind) bool {
+	switch kind {
+	case model.KindRules:
+		return true
+	case model.KindContext:
+		return s.cfg.Meta.Delete.Context
+	case model.KindIgnore:
+		return s.cfg.Meta.Delete.Ignore
+	default:
+		return false
+	}
+}
+
+// generatePath returns the agent's file for the item, or "" when the agent has none or
+// excludes it.
 func (s *SyncAI) generatePath(agent *config.Agent, kind model.Kind, stem string) string {
 	if agent == nil {
 		return ""
 	}
+	if path := s.agentPath(agent, kind, stem); path != "" && !s.excluded(agent, path) {
+		return path
+	}
+	return ""
+}
+
+func (s *SyncAI) agentPath(agent *config.Agent, kind model.Kind, stem string) string {
 	switch kind {
 	case model.KindContext:
+		p, ok := agent.Context.Parsed()
+		if ok && p.Nested {
+			path, _ := p.Path(stem)
+			return path
+		}
+		if stem != "" {
+			if takesScopedRules(agent) && !s.scopeClashes(stem) {
+				return s.rulesPath(agent, scopedRuleStem(stem))
+			}
+			return ""
+		}
+		if !ok {
+			return ""
+		}
+		// Kept as configured, so the path matches the one listed by Files
 		return agent.Context.Path
 	case model.KindIgnore:
 		return agent.Ignore.Path
 	case model.KindRules:
-		pattern := strings.TrimSpace(agent.Rules.Pattern)
-		if pattern == "" {
-			return ""
+		return s.rulesPath(agent, stem)
+	default:
+		return ""
+	}
+}
+
+// rulesPath returns the agent's rule file for the stem.
+func (s *SyncAI) rulesPath(agent *config.Agent, stem string) string {
+	patterns := agent.Rules.Parsed()
+	if len(patterns) == 0 {
+		return ""
+	}
+	// An existing copy stays where it is
+	for _, p := range patterns {
+		if path := p.Path(stem); path != "" && util.IsFileExists(s.abs(path)) {
+			return path
 		}
-		dir := filepath.Dir(pattern)
-		base := filepath.Base(pattern)
-		var filename string
-		if strings.Contains(base, "*") {
-			filename = strings.ReplaceAll(base, "*", stem)
-		} else {
-			// No wildcard in base, just use stem with the same extension as the pattern base
-			ext := filepath.Ext(base)
-			if ext == "" {
-				filename = stem
-			} else {
-				filename = stem + ext
+	}
+	// Nested rules keep their directories where the agent supports it
+	if strings.Contains(stem, "/") {
+		for _, p := range patterns {
+			if p.Recursive {
+				return p.Path(stem)
 			}
 		}
-		return filepath.Join(dir, filename)
-	default:
-		return ""
 	}
+	return patterns[0].Path(stem)
+}
+
+// holdsNoNested reports whether the agent has rules patterns but none of them can hold the
+// nested rule stem, because none is recursive or flattening.
+func holdsNoNested(agent *config.Agent, kind model.Kind, stem string) bool {
+	patterns := agent.Rules.Parsed()
+	if kind != model.KindRules || !strings.Contains(stem, "/") || len(patterns) == 0 {
+		return false
+	}
+	for _, p := range patterns {
+		if p.Path(stem) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// excluded reports whether the agent leaves the file alone because of an exclude pattern.
+// The matchers are built by configure, keyed by the lower-case agent name.
+func (s *SyncAI) excluded(agent *config.Agent, path string) bool {
+	return s.excludes[strings.ToLower(agent.Name)].Match(path)
+}
+
+// files returns the agent's files that are not excluded.
+func (s *SyncAI) files(agent *config.Agent) []string {
+	return agent.Files(s.root, s.excludes[strings.ToLower(agent.Name)], s.log)
+}
+
+// rel returns the path in the form of the configuration's paths, e.g. `AGENTS.md` for
+// `./AGENTS.md` or the absolute path of that file.
+func (s *SyncAI) rel(path string) string {
+	return util.Rel(s.root, path)
+}
+
+// abs resolves a path of the configuration against the working directory.
+func (s *SyncAI) abs(path string) string {
+	return util.Resolve(s.root, path)
+}
+
+// parseFile parses the file at a path of the configuration, keeping that path in the document.
+func (s *SyncAI) parseFile(path string) (model.Document, error) {
+	doc, err := util.ParseFile(s.abs(path))
+	doc.FileInfo.Path = path
+	// The provenance header belongs to the copy, not to the item
+	_, doc.Content, _ = cutProvenance(doc.Content)
+	return doc, err
 }
@@ -0,0 +1,128 @@
+package syncai
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/model"
+	"github.com/flowmitry/syncai/internal/util"
+)
+
+// Watch polls the agents' files every configured interval and syncs changes
+// until ctx is cancelled. A scan in progress stops after the file it is syncing.
+func (s *SyncAI) Watch(ctx context.Context) {
+	s.mu.Lock()
+	filesState := s.filesState()
+	s.reloaded = false
+	interval := s.cfg.Interval()
+	s.mu.Unlock()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.reloaded {
+				// Reload already synced everything under the new configuration
+				filesState = s.filesState()
+				s.reloaded = false
+				if s.cfg.Interval() != interval {
+					interval = s.cfg.Interval()
+					ticker.Reset(interval)
+				}
+			}
+			s.scan(ctx, filesState)
+			s.mu.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *SyncAI) filesState() map[string]string {
+	hashes := make(map[string]string)
+	for _, agent := range s.cfg.Agents {
+		for _, path := range s.files(&agent) {
+			if h, err := util.FileHash(s.abs(path)); err != nil {
+				s.log.Warn("File could not be hashed", "event", "file_error", "agent", agent.Name, "src", path, "error", err)
+			} else {
+				hashes[path] = h
+			}
+		}
+	}
+	return hashes
+}
+
+func (s *SyncAI) scan(ctx context.Context, filesState map[string]string) {
+	s.resetScopes()
+	newState := make(map[string]string)
+	for _, agent := range s.cfg.Agents {
+		for _, path := range s.files(&agent) {
+			if ctx.Err() != nil {
+				return
+			}
+			_, err := os.Stat(s.abs(path))
+			if err != nil {
+				if os.IsNotExist(err) {
+					// File may not exist yet; silently ignore
+					continue
+				}
+				s.log.Warn("File could not be read", "event", "file_error", "agent", agent.Name, "src", path, "error", err)
+				continue
+			}
+			hash, _ := util.FileHash(s.abs(path))
+			newState[path] = hash
+			prev, ok := filesState[path]
+			filesState[path] = hash
+			if !ok || hash != prev {
+				_, kind, stem := s.Identify(path)
+				props := model.Properties{Kind: kind, Stem: stem}
+				if ok {
+					s.log.Info("Detected change, syncing", itemAttrs("change", agent.Name, props, path)...)
+				} else {
+					s.log.Info("Detected new file, syncing", itemAttrs("create", agent.Name, props, path)...)
+				}
+				updatedFiles, err := s.syncFile(ctx, path)
+				if err != nil && ctx.Err() != nil {
+					// Interrupted before anything was written; the next start syncs it
+					return
+				}
+				if err != nil {
+					s.log.Error("Sync failed", itemAttrs("sync_error", agent.Name, props, path, "error", err)...)
+				}
+				for _, newPath := range updatedFiles {
+					hash, err := util.FileHash(s.abs(newPath))
+					if err != nil {
+						// Removed by the sync, e.g. from an agent the rule is not targeted at
+						delete(filesState, newPath)
+						continue
+					}
+					filesState[newPath] = hash
+				}
+			}
+		}
+	}
+	for path := range filesState {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, ok := newState[path]; !ok {
+			agent, kind, stem := s.Identify(path)
+			agentName := ""
+			if agent != nil {
+				agentName = agent.Name
+			}
+			props := model.Properties{Kind: kind, Stem: stem}
+			deletedPaths, err := s.deleteFile(ctx, path)
+			for _, deletedPath := range deletedPaths {
+				s.log.Info("Deleted file across agents", itemAttrs("delete", agentName, props, path, "dst", deletedPath)...)
+				delete(filesState, deletedPath)
+			}
+			if err != nil {
+				s.log.Error("Delete failed", itemAttrs("delete_error", agentName, props, path, "error", err)...)
+			}
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package trash
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/util"
+)
+
+const (
+	idLayout = "20060102T150405.000Z"
+	// absDir holds files whose original path is absolute or outside the working directory.
+	absDir = "_abs"
+)
+
+// Trash keeps deleted files under <dir>/<id>/<original path> so they can be restored later.
+// Original paths are relative to root unless they are absolute.
+type Trash struct {
+	root      string
+	dir       string
+	retention time.Duration
+}
+
+// Entry is a single batch of files moved to the trash together.
+type Entry struct {
+	ID    string
+	Time  time.Time
+	Files []string
+}
+
+// New opens the trash in dir, which is relative to root unless it is absolute.
+func New(root, dir string, retention time.Duration) *Trash {
+	return &Trash{root: root, dir: util.Resolve(root, dir), retention: retention}
+}
+
+// Move moves the given files into a new trash entry and prunes expired entries.
+func (t *Trash) Move(paths []string) (Entry, error) {
+	if len(paths) == 0 {
+		return Entry{}, nil
+	}
+	now := time.Now().UTC()
+	id, err := t.newID(now)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{ID: id, Time: now}
+	for _, path := range paths {
+		dst := filepath.Join(t.dir, id, t.storedPath(path))
+		if err := util.MoveFile(util.Resolve(t.root, path), dst); err != nil {
+			return entry, fmt.Errorf("move %s to trash: %w", path, err)
+		}
+		entry.Files = append(entry.Files, path)
+	}
+	if _, err := t.Prune(); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+// List returns all trash entries, newest first.
+func (t *Trash) List() ([]Entry, error) {
+	dirs, err := os.ReadDir(t.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read trash: %w", err)
+	}
+	entries := make([]Entry, 0, len(dirs))
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		ts, ok := parseID(d.Name())
+		if !ok {
+			continue
+		}
+		files, err := t.files(d.Name())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{ID: d.Name(), Time: ts, Files: files})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID > entries[j].ID
+	})
+	return entries, nil
+}
+
+// Restore moves the files of the given entry back to their original paths.
+// Existing files are never overwritten unless force is set.
+func (t *Trash) Restore(id string, force bool) ([]string, error) {
+	if _, ok := parseID(id); !ok {
+		return nil, fmt.Errorf("invalid trash id %q", id)
+	}
+	files, err := t.files(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("trash entry %s not found", id)
+	}
+	if !force {
+		for _, path := range files {
+			if util.IsFileExists(util.Resolve(t.root, path)) {
+				return nil, fmt.Errorf("%s already exists; use -force to overwrite", path)
+			}
+		}
+	}
+	restored := make([]string, 0, len(files))
+	now := time.Now()
+	for _, path := range files {
+		dst := util.Resolve(t.root, path)
+		if err := util.MoveFile(filepath.Join(t.dir, id, t.storedPath(path)), dst); err != nil {
+			return restored, fmt.Errorf("restore %s: %w", path, err)
+		}
+		// Restored files count as new, so they win over tombstones and older copies.
+		_ = os.Chtimes(dst, now, now)
+		restored = append(restored, path)
+	}
+	if err := os.RemoveAll(filepath.Join(t.dir, id)); err != nil {
+		return restored, fmt.Errorf("remove trash entry %s: %w", id, err)
+	}
+	return restored, nil
+}
+
+// Prune removes entries older than the retention period and returns their IDs.
+func (t *Trash) Prune() ([]string, error) {
+	if t.retention <= 0 {
+		return nil, nil
+	}
+	entries, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-t.retention)
+	pruned := make([]string, 0)
+	for _, e := range entries {
+		if e.Time.Before(cutoff) {
+			if err := os.RemoveAll(filepath.Join(t.dir, e.ID)); err != nil {
+				return pruned, fmt.Errorf("prune trash entry %s: %w", e.ID, err)
+			}
+			pruned = append(pruned, e.ID)
+		}
+	}
+	return pruned, nil
+}
+
+func (t *Trash) newID(now time.Time) (string, error) {
+	base := now.Format(idLayout)
+	id := base
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(t.dir, id)); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	if err := util.EnsureDir(filepath.Join(t.dir, id)); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// files returns the original paths of all files stored in the entry.
+func (t *Trash) files(id string) ([]string, error) {
+	root := filepath.Join(t.dir, id)
+	files := make([]string, 0)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, originalPath(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read trash entry %s: %w", id, err)
+	}
+	return files, nil
+}
+
+func parseID(id string) (time.Time, bool) {
+	if i := strings.LastIndex(id, "-"); i > 0 {
+		id = id[:i]
+	}
+	ts, err := time.Parse(idLayout, id)
+	return ts, err == nil
+}
+
+// storedPath maps an original path to its location inside a trash entry.
+func (t *Trash) storedPath(path string) string {
+	clean := filepath.Clean(path)
+	if !filepath.IsAbs(clean) && clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return clean
+	}
+	if abs, err := filepath.Abs(util.Resolve(t.root, clean)); err == nil {
+		clean = abs
+	}
+	clean = strings.TrimPrefix(clean, filepath.VolumeName(clean))
+	return filepath.Join(absDir, clean)
+}
+
+func originalPath(stored string) string {
+	prefix := absDir + string(filepath.Separator)
+	if strings.HasPrefix(stored, prefix) {
+		return string(filepath.Separator) + strings.TrimPrefix(stored, prefix)
+	}
+	return stored
+}
@@ -8,8 +8,31 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// Resolve returns path relative to root, or path itself when it is absolute.
+func Resolve(root, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+// Rel returns path relative to root when it lies below root, and the clean path otherwise.
+// Relative paths are taken as relative to root already, so `./AGENTS.md` becomes `AGENTS.md`.
+func Rel(root, path string) string {
+	clean := filepath.Clean(path)
+	if !filepath.IsAbs(clean) {
+		return clean
+	}
+	rel, err := filepath.Rel(root, clean)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return clean
+	}
+	return rel
+}
+
 func EnsureDir(dir string) error {
 	if dir == "" || dir == "." {
 		return nil
@@ -79,6 +102,46 @@ func WriteFile(path string, data []byte) error {
 	return nil
 }
 
+// EnsureStateDir creates a directory for SyncAI's own data and drops a
+// catch-all .gitignore into it so the data never ends up in version control.
+func EnsureStateDir(dir string) error {
+	if err := EnsureDir(dir); err != nil {
+		return err
+	}
+	ignore := filepath.Join(dir, ".gitignore")
+	if IsFileExists(ignore) {
+		return nil
+	}
+	return WriteFile(ignore, []byte("*\n"))
+}
+
+// MoveFile moves src to dst, creating the destination directory as needed.
+// When a rename is not possible (e.g. across devices) it falls back to copy and remove.
+func MoveFile(src, dst string) error {
+	if err := EnsureDir(filepath.Dir(dst)); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", src, err)
+	}
+	if err := WriteFile(dst, data); err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("remove %s: %w", src, err)
+	}
+	return nil
+}
+
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func FileHash(path string) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
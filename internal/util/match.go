@@ -0,0 +1,55 @@
+package util
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PathMatcher matches paths against patterns in .gitignore syntax. Patterns without a `/`
+// match the name of a file or directory anywhere, others are anchored to the working
+// directory, and `!` re-includes a path excluded by an earlier pattern.
+type PathMatcher struct {
+	rules []ignoreRule
+}
+
+func NewPathMatcher(patterns []string) (*PathMatcher, error) {
+	m := &PathMatcher{}
+	for _, p := range patterns {
+		if strings.TrimSpace(p) == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		r, ok := parseIgnoreRule(p)
+		if !ok {
+			return nil, fmt.Errorf("invalid pattern %q", p)
+		}
+		m.rules = append(m.rules, r)
+	}
+	return m, nil
+}
+
+// Match reports whether the file is matched. Paths are relative to the working directory;
+// absolute paths are matched without their leading separator.
+func (m *PathMatcher) Match(file string) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	clean := filepath.Clean(file)
+	clean = strings.TrimPrefix(clean, filepath.VolumeName(clean))
+	clean = strings.TrimPrefix(filepath.ToSlash(clean), "/")
+	matched := false
+	for _, r := range m.rules {
+		target := clean
+		if r.dirOnly {
+			// Only the directories of the file can match
+			if target = path.Dir(clean); target == "." {
+				continue
+			}
+		}
+		if r.re.MatchString(target) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
-	"syncai/internal/model"
+	"github.com/flowmitry/syncai/internal/model"
 
 	yaml "github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 )
 
 // ParseFile reads a Markdown file and returns a Document with populated FileInfo,
@@ -33,12 +36,26 @@ func ParseFile(path string) (model.Document, error) {
 		return doc, fmt.Errorf("read %s: %w", path, err)
 	}
 
+	metadata, body := Parse(data)
+	doc = model.Document{
+		FileInfo: model.FileInfo{
+			Path:    path,
+			ModTime: fi.ModTime(),
+		},
+		Metadata: metadata,
+		Content:  body,
+	}
+	return doc, nil
+}
+
+// Parse splits raw file content into its YAML front matter and the Markdown body.
+func Parse(data []byte) (model.DocumentMetadata, []byte) {
 	// Strip UTF-8 BOM if present
 	if len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF {
 		data = data[3:]
 	}
 
-	metadata := map[string]string{}
+	var metadata model.DocumentMetadata
 	body := data
 
 	// Detect YAML front matter only if the first line is exactly '---'
@@ -66,50 +83,7 @@ func ParseFile(path string) (model.Document, error) {
 			}
 
 			if foundEnd && yamlBuf.Len() > 0 {
-				raw := yamlBuf.Bytes()
-				var m map[string]interface{}
-				if err := yaml.Unmarshal(raw, &m); err == nil {
-					for k, v := range m {
-						if v == nil {
-							continue
-						}
-						switch vv := v.(type) {
-						case string:
-							if s, ok := cleanYAMLValue(vv); ok {
-								metadata[k] = s
-							}
-						case []interface{}:
-							// join sequence values into comma-separated metadata value
-							var parts []string
-							for _, item := range vv {
-								if s, ok := cleanYAMLValue(item); ok {
-									parts = append(parts, s)
-								}
-							}
-							if len(parts) > 0 {
-								metadata[k] = strings.Join(parts, ",")
-							}
-						default:
-							if s, ok := cleanYAMLValue(v); ok {
-								metadata[k] = s
-							}
-						}
-					}
-				} else {
-					for _, line := range strings.Split(string(raw), "\n") {
-						line = strings.TrimSpace(line)
-						if line == "" {
-							continue
-						}
-						parts := strings.SplitN(line, ":", 2)
-						if len(parts) != 2 {
-							continue
-						}
-						key := strings.TrimSpace(parts[0])
-						val := strings.TrimSpace(parts[1])
-						metadata[key] = val
-					}
-				}
+				metadata = parseFrontMatter(yamlBuf.Bytes())
 			} else {
 				// If we didn't find the end delimiter, reset body to full data
 				body = data
@@ -117,39 +91,113 @@ func ParseFile(path string) (model.Document, error) {
 		}
 	}
 
-	doc = model.Document{
-		FileInfo: model.FileInfo{
-			Path:    path,
-			ModTime: fi.ModTime(),
-		},
-		Metadata: model.DocumentMetadata{
-			Raw: metadata,
-		},
-		Content: body,
+	return metadata, body
+}
+
+// parseFrontMatter parses YAML front matter into ordered entries that keep their nodes,
+// comments included. Front matter that is not valid YAML falls back to plain "key: value" lines.
+func parseFrontMatter(raw []byte) model.DocumentMetadata {
+	file, err := parser.ParseBytes(raw, parser.ParseComments)
+	if err != nil {
+		// Cursor writes globs such as `**/*.go` unquoted, which is not valid YAML; quote them so
+		// nested values of the other keys are still parsed.
+		file, err = parser.ParseBytes(quoteAliasValues(raw), parser.ParseComments)
 	}
-	return doc, nil
+	if err != nil || len(file.Docs) == 0 {
+		return parseFrontMatterLines(raw)
+	}
+	var values []*ast.MappingValueNode
+	switch body := file.Docs[0].Body.(type) {
+	case *ast.MappingNode:
+		values = body.Values
+	case *ast.MappingValueNode:
+		values = []*ast.MappingValueNode{body}
+	case nil, *ast.CommentGroupNode:
+		return model.DocumentMetadata{}
+	default:
+		return parseFrontMatterLines(raw)
+	}
+
+	metadata := model.DocumentMetadata{Entries: make([]model.MetadataEntry, 0, len(values))}
+	for _, mv := range values {
+		var key string
+		if err := yaml.NodeToValue(mv.Key, &key); err != nil {
+			key = mv.Key.String()
+		}
+		var value any
+		if err := yaml.NodeToValue(mv.Value, &value); err != nil {
+			// Unquoted globs such as `*.go` parse as unresolvable aliases; keep their text.
+			value = strings.TrimSpace(mv.Value.String())
+		}
+		metadata.Entries = append(metadata.Entries, model.MetadataEntry{Key: key, Value: value, Node: mv})
+	}
+	return metadata
 }
 
-func cleanYAMLValue(value interface{}) (string, bool) {
-	switch v := value.(type) {
-	case string:
-		sv := strings.TrimSpace(v)
-		if sv == "" || strings.EqualFold(sv, "<nil>") {
-			return "", false
+// quoteAliasValues quotes the values that start with `*` and would otherwise be read as
+// aliases: mapping values, block list items and the items of flow lists.
+func quoteAliasValues(raw []byte) []byte {
+	lines := strings.Split(string(raw), "\n")
+	for i, line := range lines {
+		lines[i] = quoteAliasLine(line)
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func quoteAliasLine(line string) string {
+	rest := strings.TrimLeft(line, " \t")
+	if rest == "" || rest[0] == '#' {
+		return line
+	}
+	// Skip list item markers, then the key of a mapping value
+	for rest == "-" || strings.HasPrefix(rest, "- ") {
+		rest = strings.TrimLeft(rest[1:], " \t")
+	}
+	if rest != "" && rest[0] != '*' && rest[0] != '[' {
+		_, value, ok := strings.Cut(rest, ": ")
+		if !ok {
+			return line
 		}
-		return sv, true
-	case bool:
-		return fmt.Sprintf("%t", v), true
-	case int, int8, int16, int32, int64:
-		return fmt.Sprintf("%d", v), true
-	case uint, uint8, uint16, uint32, uint64:
-		return fmt.Sprintf("%d", v), true
-	case float32, float64:
-		return fmt.Sprintf("%v", v), true
-	case nil:
-		return "", false
+		rest = strings.TrimLeft(value, " \t")
+	}
+	value, comment, commented := strings.Cut(rest, " #")
+	value = strings.TrimRight(value, " \t")
+	var quoted string
+	switch {
+	case strings.HasPrefix(value, "*"):
+		quoted = strconv.Quote(value)
+	case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+		items := strings.Split(value[1:len(value)-1], ",")
+		for j, item := range items {
+			if item = strings.TrimSpace(item); strings.HasPrefix(item, "*") {
+				item = strconv.Quote(item)
+			}
+			items[j] = item
+		}
+		quoted = "[" + strings.Join(items, ", ") + "]"
 	default:
-		// Ignore slices, maps, structs, etc.
-		return "", false
+		return line
+	}
+	if commented {
+		quoted += " #" + comment
+	}
+	return line[:len(line)-len(rest)] + quoted
+}
+
+func parseFrontMatterLines(raw []byte) model.DocumentMetadata {
+	var metadata model.DocumentMetadata
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		val := strings.TrimSpace(parts[1])
+		metadata.Entries = append(metadata.Entries, model.MetadataEntry{Key: key, Value: val})
 	}
+	return metadata
 }
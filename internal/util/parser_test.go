@@ -0,0 +1,56 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUnquotedGlobs(t *testing.T) {
+	tests := []struct {
+		name  string
+		front string
+		globs any
+	}{
+		{"mapping value", "globs: **/*.go", "**/*.go"},
+		{"block list", "globs:\n  - **/*.go\n  - *.ts # scripts", []any{"**/*.go", "*.ts"}},
+		{"unindented block list", "globs:\n- **/*.go\n- docs/*.md", []any{"**/*.go", "docs/*.md"}},
+		{"flow list", "globs: [**/*.go, *.ts, docs/*.md]", []any{"**/*.go", "*.ts", "docs/*.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "---\n" + tt.front + "\nsyncai:\n  skip: [copilot]\n---\nBody\n"
+			metadata, body := Parse([]byte(data))
+			if string(body) != "Body\n" {
+				t.Errorf("body is %q", body)
+			}
+			values := metadata.Values()
+			if got := values["globs"]; !reflect.DeepEqual(got, tt.globs) {
+				t.Errorf("globs are %#v, want %#v", got, tt.globs)
+			}
+			// The nested map must survive, not be flattened by the line parser
+			want := map[string]any{"skip": []any{"copilot"}}
+			if got := values["syncai"]; !reflect.DeepEqual(got, want) {
+				t.Errorf("syncai is %#v, want %#v", got, want)
+			}
+			if len(values) != 2 {
+				t.Errorf("entries are %v, want globs and syncai", values)
+			}
+		})
+	}
+}
+
+func TestQuoteAliasLine(t *testing.T) {
+	tests := map[string]string{
+		"globs: **/*.go":           `globs: "**/*.go"`,
+		"  - *.ts # scripts":       `  - "*.ts" # scripts`,
+		"paths: [*.go, docs/*.md]": `paths: ["*.go", docs/*.md]`,
+		"description: Use: *this*": "description: Use: *this*",
+		"# globs: **/*.go":         "# globs: **/*.go",
+		"globs:":                   "globs:",
+	}
+	for line, want := range tests {
+		if got := quoteAliasLine(line); got != want {
+			t.Errorf("quoteAliasLine(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
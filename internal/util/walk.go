@@ -0,0 +1,185 @@
+package util
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SkipDir reports whether recursive patterns never descend into a directory with this name.
+func SkipDir(name string) bool {
+	switch name {
+	case ".git", ".syncai", "node_modules", "vendor":
+		return true
+	}
+	return false
+}
+
+// WalkFiles calls fn for every file below dir, which is relative to root unless it is
+// absolute, skipping the directories of SkipDir and everything excluded by the .gitignore
+// files of root. The paths passed to fn start with dir, just like the paths of filepath.Glob.
+func WalkFiles(root, dir string, fn func(path string) error) error {
+	ignore := newGitIgnore(root)
+	base := Resolve(root, dir)
+	return filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+		p = filepath.Join(dir, rel)
+		if d.IsDir() {
+			if rel != "." && (SkipDir(d.Name()) || ignore.Ignored(p, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ignore.Ignored(p, false) {
+			return nil
+		}
+		return fn(p)
+	})
+}
+
+// gitIgnore matches paths relative to root against the .gitignore files of their parent
+// directories. It supports the usual syntax: `*`, `?`, `**`, character classes,
+// leading `/` anchors, trailing `/` for directories and `!` negation.
+type gitIgnore struct {
+	root  string
+	mu    sync.Mutex
+	rules map[string][]ignoreRule
+}
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+func newGitIgnore(root string) *gitIgnore {
+	return &gitIgnore{root: root, rules: make(map[string][]ignoreRule)}
+}
+
+// Ignored reports whether the path is excluded. Paths outside root never are.
+func (g *gitIgnore) Ignored(p string, isDir bool) bool {
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if filepath.IsAbs(p) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return false
+	}
+	ignored := false
+	dir := "."
+	rel := clean
+	for {
+		for _, r := range g.load(dir) {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.re.MatchString(rel) {
+				ignored = !r.negate
+			}
+		}
+		first, rest, ok := strings.Cut(rel, "/")
+		if !ok {
+			return ignored
+		}
+		dir = path.Join(dir, first)
+		rel = rest
+	}
+}
+
+func (g *gitIgnore) load(dir string) []ignoreRule {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if rules, ok := g.rules[dir]; ok {
+		return rules
+	}
+	rules := make([]ignoreRule, 0)
+	if f, err := os.Open(filepath.Join(g.root, filepath.FromSlash(dir), ".gitignore")); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if r, ok := parseIgnoreRule(scanner.Text()); ok {
+				rules = append(rules, r)
+			}
+		}
+		f.Close()
+	}
+	g.rules[dir] = rules
+	return rules
+}
+
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var r ignoreRule
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return r, false
+	}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return r, false
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(line):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(line[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	// A matched directory excludes everything below it
+	sb.WriteString("(?:/.*)?$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return r, false
+	}
+	r.re = re
+	return r, true
+}
@@ -0,0 +1,131 @@
+package syncai
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/flowmitry/syncai/internal/config"
+)
+
+// Configuration types, as read from syncai.json.
+type (
+	Config           = config.Config
+	Meta             = config.Meta
+	Agent            = config.Agent
+	Rules            = config.Rules
+	Context          = config.Context
+	Ignore           = config.Ignore
+	GeneratorCommand = config.Generator
+	TrashConfig      = config.Trash
+	JournalConfig    = config.Journal
+	DeleteConfig     = config.Delete
+	Workspace        = config.Workspace
+	Project          = config.Project
+)
+
+// LoadConfig reads and validates a configuration file. A non-empty workDir overrides the
+// working directory of the file.
+func LoadConfig(path, workDir string) (Config, error) {
+	return config.Load(path, workDir)
+}
+
+// LoadWorkspace reads a workspace file listing several project configurations.
+func LoadWorkspace(path string) (Workspace, error) {
+	return config.LoadWorkspace(path)
+}
+
+// ConfigTemplate returns the default configuration written by `syncai init`.
+func ConfigTemplate() []byte {
+	return config.Template()
+}
+
+// GlobalConfigTemplate returns the user-level configuration written by `syncai init -global`.
+func GlobalConfigTemplate() []byte {
+	return config.GlobalTemplate()
+}
+
+// GlobalConfigPath returns the location of the user-level configuration,
+// ~/.config/syncai/syncai.json unless XDG_CONFIG_HOME is set.
+func GlobalConfigPath() (string, error) {
+	return config.GlobalPath()
+}
+
+// DefaultConfig returns the default configuration with all supported agents.
+func DefaultConfig() (Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(config.Template(), &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse default config: %w", err)
+	}
+	return cfg, nil
+}
+
+// ConfigBuilder builds a configuration in code instead of reading syncai.json.
+type ConfigBuilder struct {
+	cfg Config
+}
+
+func NewConfig() *ConfigBuilder {
+	return &ConfigBuilder{}
+}
+
+// WorkingDir sets the project directory that relative paths are resolved against. It
+// defaults to the current directory at the time New is called.
+func (b *ConfigBuilder) WorkingDir(dir string) *ConfigBuilder {
+	b.cfg.Meta.WorkingDir = dir
+	return b
+}
+
+// Interval sets how often Watch polls for changes, rounded down to whole seconds.
+func (b *ConfigBuilder) Interval(d time.Duration) *ConfigBuilder {
+	b.cfg.Meta.Interval = int(d / time.Second)
+	return b
+}
+
+// MetadataPolicy sets how rule metadata is merged: "changed" (default) or "newest-value".
+func (b *ConfigBuilder) MetadataPolicy(policy string) *ConfigBuilder {
+	b.cfg.Meta.Metadata = policy
+	return b
+}
+
+func (b *ConfigBuilder) Trash(t TrashConfig) *ConfigBuilder {
+	b.cfg.Meta.Trash = t
+	return b
+}
+
+func (b *ConfigBuilder) Journal(j JournalConfig) *ConfigBuilder {
+	b.cfg.Meta.Journal = j
+	return b
+}
+
+func (b *ConfigBuilder) Delete(d DeleteConfig) *ConfigBuilder {
+	b.cfg.Meta.Delete = d
+	return b
+}
+
+// Agent adds an agent, replacing an earlier one with the same name.
+func (b *ConfigBuilder) Agent(a Agent) *ConfigBuilder {
+	for i := range b.cfg.Agents {
+		if b.cfg.Agents[i].Name == a.Name {
+			b.cfg.Agents[i] = a
+			return b
+		}
+	}
+	b.cfg.Agents = append(b.cfg.Agents, a)
+	return b
+}
+
+// Build validates the configuration and returns it. Disabled agents are left out.
+func (b *ConfigBuilder) Build() (Config, error) {
+	cfg := b.cfg
+	cfg.Agents = make([]Agent, 0, len(b.cfg.Agents))
+	for _, a := range b.cfg.Agents {
+		if !a.Disabled {
+			cfg.Agents = append(cfg.Agents, a)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,187 @@
+// Package syncai embeds SyncAI: it keeps the rules, context and ignore files of several
+// AI coding agents in sync.
+//
+//	cfg, err := syncai.NewConfig().
+//		WorkingDir(".").
+//		Agent(syncai.Agent{Name: "cursor", Rules: syncai.Rules{Pattern: ".cursor/rules/*.mdc"}}).
+//		Agent(syncai.Agent{Name: "copilot", Rules: syncai.Rules{Pattern: ".github/instructions/*.instruction.md"}}).
+//		Build()
+//	if err != nil {
+//		return err
+//	}
+//	s, err := syncai.New(cfg)
+//	if err != nil {
+//		return err
+//	}
+//	return s.SyncAll(ctx)
+//
+// Paths in the configuration are relative to its working directory, which New resolves to
+// an absolute root once; changing the current directory afterwards has no effect.
+package syncai
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	isyncai "github.com/flowmitry/syncai/internal/syncai"
+	"github.com/flowmitry/syncai/internal/trash"
+)
+
+// SyncAI syncs the files of the configured agents. Its methods are safe for concurrent use,
+// also while Watch is running: calls that read or write files wait for the current scan.
+type SyncAI struct {
+	mu    sync.Mutex
+	cfg   Config
+	inner *isyncai.SyncAI
+}
+
+// Option customizes a SyncAI instance created by New.
+type Option func(*options)
+
+type options struct {
+	inner []isyncai.Option
+}
+
+// WithLogger sets the logger for sync events. The default is slog.Default().
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.inner = append(o.inner, isyncai.WithLogger(l))
+	}
+}
+
+// WithGenerator uses gen to parse and render the rules of the named agent, overriding the
+// built-in format and any generator command in the configuration.
+func WithGenerator(agent string, gen RulesGenerator) Option {
+	return func(o *options) {
+		o.inner = append(o.inner, isyncai.WithGenerator(agent, gen))
+	}
+}
+
+// New validates the configuration and creates a SyncAI instance for it.
+func New(cfg Config, opts ...Option) (*SyncAI, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &SyncAI{cfg: cfg, inner: isyncai.New(cfg, o.inner...)}, nil
+}
+
+// Config returns the configuration the instance was created with.
+func (s *SyncAI) Config() Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+// Root returns the absolute working directory the paths of the configuration are resolved
+// against. It is fixed when the instance is created, so changing the current directory of
+// the process later has no effect.
+func (s *SyncAI) Root() string {
+	return s.inner.Root()
+}
+
+// SyncOption changes a single SyncAll call.
+type SyncOption func(*syncOptions)
+
+type syncOptions struct {
+	dryRun bool
+}
+
+// DryRun makes SyncAll report the files it would write as "would_sync" events instead of
+// writing them.
+func DryRun() SyncOption {
+	return func(o *syncOptions) {
+		o.dryRun = true
+	}
+}
+
+// SyncAll brings every item to its newest version across all agents. It stops between
+// items when ctx is cancelled. Failures of single items are reported as events.
+func (s *SyncAI) SyncAll(ctx context.Context, opts ...SyncOption) error {
+	o := &syncOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.dryRun {
+		_, err := s.inner.DryRun(ctx)
+		return err
+	}
+	return s.inner.SyncAll(ctx)
+}
+
+// Sync propagates the given file, which was created or changed, to the other agents and
+// returns the paths of all copies. Cancelling ctx aborts the sync until the first file is
+// written; after that the batch is completed or, if a write fails, rolled back.
+func (s *SyncAI) Sync(ctx context.Context, path string) ([]string, error) {
+	return s.inner.Sync(ctx, path)
+}
+
+// Delete propagates the deletion of the given file to the other agents.
+func (s *SyncAI) Delete(ctx context.Context, path string) ([]string, error) {
+	return s.inner.Delete(ctx, path)
+}
+
+// Watch polls for changes and syncs them until ctx is cancelled. It returns once the
+// file being synced at that moment is done.
+func (s *SyncAI) Watch(ctx context.Context) {
+	s.inner.Watch(ctx)
+}
+
+// Reload switches to a new configuration. Items of added agents, and of agent sections
+// that changed, get an initial sync. A running Watch picks it up after its current scan.
+// An invalid configuration is rejected and the current one is kept.
+func (s *SyncAI) Reload(ctx context.Context, cfg Config) error {
+	if err := s.inner.Reload(ctx, cfg); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+	return nil
+}
+
+// Subscribe calls fn for every sync event until the returned function is called.
+// fn runs on the syncing goroutine and must not block or call other methods of s.
+func (s *SyncAI) Subscribe(fn func(Event)) func() {
+	return s.inner.Subscribe(fn)
+}
+
+// Items returns every item with at least one existing copy.
+func (s *SyncAI) Items() []Item {
+	return s.inner.Items()
+}
+
+// Preview returns what syncing the given file would write, without writing anything.
+func (s *SyncAI) Preview(path string) ([]Preview, error) {
+	return s.inner.Preview(path)
+}
+
+// Status reports for every item which agents are in sync with its newest copy.
+func (s *SyncAI) Status() Report {
+	return s.inner.Status()
+}
+
+// Undo rolls back the sync with the given journal id, or the last one when id is empty.
+func (s *SyncAI) Undo(id string, force bool) (JournalRecord, error) {
+	return s.inner.Undo(id, force)
+}
+
+// Journal returns the journal of syncs, or nil when it is disabled.
+func (s *SyncAI) Journal() *Journal {
+	return s.inner.Journal()
+}
+
+// Trash returns the trash for deleted copies, or nil when it is disabled.
+func (s *SyncAI) Trash() *Trash {
+	return s.inner.Trash()
+}
+
+// OpenTrash opens the trash of a project even when it is disabled, e.g. to restore files
+// deleted before it was turned off.
+func OpenTrash(cfg Config) *Trash {
+	return trash.New(cfg.WorkingDir(), cfg.TrashDir(), cfg.TrashRetention())
+}
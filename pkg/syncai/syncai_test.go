@@ -0,0 +1,75 @@
+package syncai_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flowmitry/syncai/pkg/syncai"
+)
+
+// TestConcurrentUseDuringWatch is meant for -race: the public methods must be safe to call
+// while Watch scans and a reload swaps the configuration.
+func TestConcurrentUseDuringWatch(t *testing.T) {
+	root := t.TempDir()
+	rule := filepath.Join(root, ".cursor/rules/go.mdc")
+	if err := os.MkdirAll(filepath.Dir(rule), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rule, []byte("---\ndescription: Go\n---\nUse gofmt.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := syncai.NewConfig().
+		WorkingDir(root).
+		Interval(time.Second).
+		Agent(syncai.Agent{Name: "cursor", Rules: syncai.Rules{Pattern: ".cursor/rules/*.mdc"}}).
+		Agent(syncai.Agent{Name: "cline", Rules: syncai.Rules{Pattern: ".clinerules/*.md"}}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := syncai.New(cfg, syncai.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.Watch(ctx)
+	}()
+	for i := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				switch i {
+				case 0:
+					s.Status()
+					s.Items()
+				case 1:
+					if _, err := s.Sync(ctx, rule); err != nil && ctx.Err() == nil {
+						t.Error(err)
+					}
+				case 2:
+					if err := s.Reload(ctx, s.Config()); err != nil && ctx.Err() == nil {
+						t.Error(err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := os.Stat(filepath.Join(root, ".clinerules/go.md")); err != nil {
+		t.Errorf("rule was not synced: %v", err)
+	}
+}
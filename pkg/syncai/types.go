@@ -0,0 +1,71 @@
+package syncai
+
+import (
+	"github.com/flowmitry/syncai/internal/generator"
+	"github.com/flowmitry/syncai/internal/journal"
+	"github.com/flowmitry/syncai/internal/model"
+	isyncai "github.com/flowmitry/syncai/internal/syncai"
+	"github.com/flowmitry/syncai/internal/trash"
+)
+
+// Rule metadata and the generator interface for custom agent formats.
+type (
+	RulesGenerator   = generator.RulesGenerator
+	RulesMetadata    = model.RulesMetadata
+	DocumentMetadata = model.DocumentMetadata
+	MetadataEntry    = model.MetadataEntry
+	Activation       = model.Activation
+	Kind             = model.Kind
+	Properties       = model.Properties
+)
+
+const (
+	ActivationUnknown   = model.ActivationUnknown
+	ActivationAlways    = model.ActivationAlways
+	ActivationGlobs     = model.ActivationGlobs
+	ActivationRequested = model.ActivationRequested
+	ActivationManual    = model.ActivationManual
+)
+
+const (
+	KindRules   = model.KindRules
+	KindContext = model.KindContext
+	KindIgnore  = model.KindIgnore
+)
+
+// Sync state, as returned by Items, Preview and Status.
+type (
+	Event        = isyncai.Event
+	Item         = isyncai.Item
+	Copy         = isyncai.Copy
+	Preview      = isyncai.Preview
+	Report       = isyncai.Report
+	ItemStatus   = isyncai.ItemStatus
+	AgentStatus  = isyncai.AgentStatus
+	MetadataDiff = isyncai.MetadataDiff
+)
+
+// Trash and journal of a project.
+type (
+	Trash         = trash.Trash
+	TrashEntry    = trash.Entry
+	Journal       = journal.Journal
+	JournalRecord = journal.Record
+	JournalChange = journal.Change
+)
+
+const (
+	RecordSync = journal.RecordSync
+	RecordUndo = journal.RecordUndo
+)
+
+// BuiltinGenerator returns the rules generator SyncAI uses for the named agent.
+func BuiltinGenerator(agent string) RulesGenerator {
+	return generator.GetRulesGenerator(agent)
+}
+
+// ParseGlobs normalizes file patterns as the built-in generators do, e.g. `*.{ts,tsx}`
+// becomes `**/*.ts`, `**/*.tsx`.
+func ParseGlobs(values []string) []string {
+	return generator.ParseGlobs(values)
+}